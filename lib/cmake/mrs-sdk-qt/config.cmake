@@ -202,18 +202,29 @@ if(DEFINED MRS_SDK_QT_CONSUMER_TARGET)
     set(MRS_SDK_QT_INCLUDE_DIRS "${MRS_SDK_QT_ROOT}/${MRS_SDK_QT_VERSION}/include")
 
     # The exact path to the static library inside MRS_SDK_QT_LIBRARY_DIR_BASE is defined as follows:
-    # <qt-maj-ver>/<os-target>/<sys-name>_<processor-type>_<device-target>/
+    # <qt-maj-ver>/<os-target>/<sys-name>_<processor-type>_<device-target>/<build-type>/
     # This ensures that all libraries have a unique installation location that can be determined programmatically.
     set(_lib_os_path "${MRS_SDK_QT_LIBRARY_DIR_BASE}/qt${MRS_SDK_QT_QT_MAJOR_VERSION}/${_mrs_sdk_qt_target_os}/")
     string(TOLOWER "${CMAKE_SYSTEM_NAME}" _sys_name)
     set(_lib_target_dirname "${_sys_name}_${_mrs_sdk_qt_processor_lower}_${_mrs_sdk_qt_target_device}")
-    set(MRS_SDK_QT_LIBRARY_DIR "${_lib_os_path}/${_lib_target_dirname}")
+
+    # The SDK ships debug, release and relwithdebinfo libraries.
+    # MinSizeRel consumers link the release library, and consumers without a build type keep the historical debug library.
+    string(TOLOWER "${CMAKE_BUILD_TYPE}" _mrs_sdk_qt_build_type)
+    if(_mrs_sdk_qt_build_type STREQUAL "minsizerel")
+        set(_mrs_sdk_qt_build_type "release")
+    elseif(NOT _mrs_sdk_qt_build_type MATCHES "^(debug|release|relwithdebinfo)$")
+        set(_mrs_sdk_qt_build_type "debug")
+    endif()
+    set(MRS_SDK_QT_LIBRARY_DIR "${_lib_os_path}/${_lib_target_dirname}/${_mrs_sdk_qt_build_type}")
 
     if(TARGET ${MRS_SDK_QT_CONSUMER_TARGET})
         message(NOTICE "Configuring MRS SDK for target ${MRS_SDK_QT_CONSUMER_TARGET}...")
 
         # Find the static library file in the specified location inside the SDK installation tree.
         # Using REQUIRED ensures that the entire script will fail if it is not found.
+        # The cached result is cleared first so that changing the build type picks up the matching library.
+        unset(MRS_SDK_QT_LIBS CACHE)
         find_library(MRS_SDK_QT_LIBS
             NAMES ${MRS_SDK_QT_LIB_NAME}
             PATHS "${MRS_SDK_QT_LIBRARY_DIR}"
@@ -0,0 +1,100 @@
+# Qt6 Yocto toolchain helper.
+# NOTE: this helper is only meant to be used with the Qt6 Yocto kit for NeuralPlex devices.
+# Use the yocto-qt5.cmake helper for the MConn Qt5 kit.
+
+# First, set CMake variables necessary for the Yocto-Qt toolchain.
+# We have to start from the included toolchain file and then add some extra stuff that it doesn't do correctly.
+
+# Automatically source the Yocto toolchain setup script using a path provided
+# by the current configure invocation.
+# Auto-sourcing is not typically recommended, but it is desirable for our use case because
+# we don't want to put extra burden on less experienced users.
+if (NOT DEFINED ENV{OE_CMAKE_TOOLCHAIN_FILE})
+    message(NOTICE "Yocto environment not found. Auto-sourcing environment...")
+
+    # Use values already provided by the current configure invocation.
+    # This keeps repo-local build-local workflows self-contained and avoids
+    # introducing a bootstrap dependency on an already-installed SDK manager.
+    set(_yocto_qt6_env_setup_script "")
+    if (DEFINED YOCTO_QT6_ENV_SETUP_SCRIPT AND NOT "${YOCTO_QT6_ENV_SETUP_SCRIPT}" STREQUAL "")
+        set(_yocto_qt6_env_setup_script "${YOCTO_QT6_ENV_SETUP_SCRIPT}")
+    elseif (DEFINED ENV{YOCTO_QT6_ENV_SETUP_SCRIPT} AND NOT "$ENV{YOCTO_QT6_ENV_SETUP_SCRIPT}" STREQUAL "")
+        set(_yocto_qt6_env_setup_script "$ENV{YOCTO_QT6_ENV_SETUP_SCRIPT}")
+    endif()
+
+    if (_yocto_qt6_env_setup_script STREQUAL "")
+        message(FATAL_ERROR
+            "Could not determine Yocto setup script path.\n"
+            "Pass -DYOCTO_QT6_ENV_SETUP_SCRIPT=/path/to/setup-script when configuring."
+        )
+    endif()
+
+    # Source the setup script in a subshell and capture the resulting environment.
+    execute_process(
+        COMMAND bash -c "source \"${_yocto_qt6_env_setup_script}\" && env"
+        OUTPUT_VARIABLE _yocto_qt6_env_output
+        RESULT_VARIABLE _yocto_qt6_env_source_result
+    )
+    if (NOT _yocto_qt6_env_source_result EQUAL 0)
+        message(FATAL_ERROR "Failed to source Yocto setup script: ${_yocto_qt6_env_setup_script}")
+    endif()
+
+    # Parse and import only the required environment variables.
+    # The Qt6 toolchain file shipped with the SDK locates the host tools through OECORE_NATIVE_SYSROOT,
+    # and the base compiler flags must be preserved for the same reason as in the Qt5 helper.
+    string(REPLACE "\n" ";" _yocto_qt6_env_lines "${_yocto_qt6_env_output}")
+    foreach(_LINE IN LISTS _yocto_qt6_env_lines)
+        if (_LINE MATCHES "^(OE_CMAKE_TOOLCHAIN_FILE|OECORE_TARGET_SYSROOT|OECORE_NATIVE_SYSROOT|CFLAGS|CXXFLAGS)=(.*)$")
+            set(ENV{${CMAKE_MATCH_1}} "${CMAKE_MATCH_2}")
+        endif()
+    endforeach()
+
+    # Make sure all of the necessary environment variables were defined.
+    if (NOT DEFINED ENV{OE_CMAKE_TOOLCHAIN_FILE} OR "$ENV{OE_CMAKE_TOOLCHAIN_FILE}" STREQUAL "")
+        message(FATAL_ERROR "Failed to read OE_CMAKE_TOOLCHAIN_FILE from Yocto setup script: ${_yocto_qt6_env_setup_script}")
+    endif()
+    if (NOT DEFINED ENV{OECORE_TARGET_SYSROOT} OR "$ENV{OECORE_TARGET_SYSROOT}" STREQUAL "")
+        message(FATAL_ERROR "Failed to read OECORE_TARGET_SYSROOT from Yocto setup script: ${_yocto_qt6_env_setup_script}")
+    endif()
+    if (NOT DEFINED ENV{OECORE_NATIVE_SYSROOT} OR "$ENV{OECORE_NATIVE_SYSROOT}" STREQUAL "")
+        message(FATAL_ERROR "Failed to read OECORE_NATIVE_SYSROOT from Yocto setup script: ${_yocto_qt6_env_setup_script}")
+    endif()
+    if (NOT DEFINED ENV{CFLAGS} OR "$ENV{CFLAGS}" STREQUAL "")
+        message(FATAL_ERROR "Failed to read CFLAGS from Yocto setup script: ${_yocto_qt6_env_setup_script}")
+    endif()
+    if (NOT DEFINED ENV{CXXFLAGS} OR "$ENV{CXXFLAGS}" STREQUAL "")
+        message(FATAL_ERROR "Failed to read CXXFLAGS from Yocto setup script: ${_yocto_qt6_env_setup_script}")
+    endif()
+endif()
+
+# Run the kit's toolchain file.
+include($ENV{OE_CMAKE_TOOLCHAIN_FILE})
+
+# The Cortex-A53 flags are already part of CFLAGS/CXXFLAGS for aarch64, so only the base flags need restoring here.
+set(CMAKE_C_FLAGS "$ENV{CFLAGS}" CACHE STRING "" FORCE)
+set(CMAKE_CXX_FLAGS "$ENV{CXXFLAGS}" CACHE STRING "" FORCE)
+# If this isn't set, CMake will try to test the ARM compiler, which fails because the ARM compiler's output will be for ARM and not x86.
+# Setting this variable is the solution added by CMake to skip this check when cross-compiling.
+set(CMAKE_TRY_COMPILE_TARGET_TYPE "STATIC_LIBRARY" CACHE STRING "Skips cross-compiler checks" FORCE)
+set(CMAKE_PREFIX_PATH "$ENV{OECORE_TARGET_SYSROOT}/usr" CACHE PATH "" FORCE)
+# Qt6 cross builds need the host Qt tools (moc, rcc, etc.) from the native sysroot.
+if (NOT DEFINED QT_HOST_PATH)
+    set(QT_HOST_PATH "$ENV{OECORE_NATIVE_SYSROOT}/usr" CACHE PATH "Path to host Qt tools" FORCE)
+endif()
+# Useful debug output.
+set(CMAKE_EXPORT_COMPILE_COMMANDS TRUE CACHE BOOL "" FORCE)
+
+# Set the expected Qt versions based on device target.
+set(MRS_SDK_QT_QT_MAJOR_VERSION "6" CACHE STRING "Required Qt toolchain major version" FORCE)
+set(MRS_SDK_QT_EXPECTED_QT_VERSION_NEURALPLEX "6.8.1" CACHE STRING "Expected NeuralPlex Qt version" FORCE)
+
+# Set the OS identifier variables.
+set(MRS_SDK_QT_TARGET_OS "Yocto" CACHE STRING "Target OS identifier" FORCE)
+set(MRS_SDK_QT_OS_YOCTO TRUE CACHE BOOL "Target OS is Yocto" FORCE)
+set(MRS_SDK_QT_OS_BUILDROOT FALSE CACHE BOOL "Target OS is Buildroot" FORCE)
+set(MRS_SDK_QT_OS_DESKTOP FALSE CACHE BOOL "Target OS is desktop" FORCE)
+
+# Yocto Qt6 builds target 64-bit ARM devices.
+set(CMAKE_SYSTEM_NAME "Linux" CACHE STRING "Target OS" FORCE)
+set(CMAKE_SYSTEM_PROCESSOR "aarch64" CACHE STRING "Target processor" FORCE)
+set(CMAKE_CROSSCOMPILING TRUE CACHE BOOL "Cross-compiling" FORCE)
@@ -132,7 +132,7 @@ _mrs_sdk_qt_qt_version = $$MRS_SDK_QT_EXPECTED_QT_VERSION
 ###########################################################################################################################################
 _mrs_sdk_qt_is_arm = 0
 _mrs_sdk_qt_processor_lower = $$lower($$MRS_SDK_QT_SYSTEM_PROCESSOR)
-contains(_mrs_sdk_qt_processor_lower, "arm") {
+contains(_mrs_sdk_qt_processor_lower, "arm|aarch64|cortex.*") {
     _mrs_sdk_qt_is_arm = 1
 }
 
@@ -182,13 +182,23 @@ message("Environment: target OS: $$MRS_SDK_QT_TARGET_OS")
     MRS_SDK_QT_INCLUDE_DIRS = $$MRS_SDK_QT_ROOT/$${MRS_SDK_QT_VERSION}/include
 
     # The exact path to the static library inside MRS_SDK_QT_LIBRARY_DIR_BASE is defined as follows:
-    # <qt-maj-ver>/<os-target>/<sys-name>_<processor-type>_<device-target>/
+    # <qt-maj-ver>/<os-target>/<sys-name>_<processor-type>_<device-target>/<build-type>/
     # This ensures that all libraries have a unique installation location that can be determined programmatically.
     _lib_os_path = $$MRS_SDK_QT_LIBRARY_DIR_BASE/qt$${MRS_SDK_QT_QT_MAJOR_VERSION}/$${_mrs_sdk_qt_target_os}
     _sys_name = $$lower($$MRS_SDK_QT_SYSTEM_NAME)
     _lib_target_dirname = $${_sys_name}_$${_mrs_sdk_qt_processor_lower}_$$_mrs_sdk_qt_target_device
 
-    MRS_SDK_QT_LIBRARY_DIR = $$_lib_os_path/$$_lib_target_dirname
+    # QMake only distinguishes debug from release.
+    # Release builds with separate debug info (Qt Creator's "Profile" configuration) link the relwithdebinfo library.
+    CONFIG(debug, debug|release) {
+        _mrs_sdk_qt_build_type = debug
+    } else:force_debug_info {
+        _mrs_sdk_qt_build_type = relwithdebinfo
+    } else {
+        _mrs_sdk_qt_build_type = release
+    }
+
+    MRS_SDK_QT_LIBRARY_DIR = $$_lib_os_path/$$_lib_target_dirname/$$_mrs_sdk_qt_build_type
 
     # Add the SDK's compiled libraries and include paths.
     LIBS += -L$$MRS_SDK_QT_LIBRARY_DIR -l$$MRS_SDK_QT_LIB_NAME
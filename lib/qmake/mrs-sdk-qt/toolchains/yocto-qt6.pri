@@ -0,0 +1,34 @@
+# Qt6 Yocto toolchain helper.
+# NOTE: this helper is only meant to be used with the Qt6 Yocto kit for NeuralPlex devices.
+# Use the yocto-qt5.pri helper for the MConn Qt5 kit.
+
+# Automatically source the Yocto toolchain setup script if the environment is not already set up.
+# Auto-sourcing is not typically recommended, but it is desirable for our use case because
+# we don't want to put extra burden on less experienced users.
+OE_CMAKE_TOOLCHAIN_FILE = $$(OE_CMAKE_TOOLCHAIN_FILE)
+isEmpty(OE_CMAKE_TOOLCHAIN_FILE) {
+    message("Yocto environment not found. Auto-sourcing environment...")
+
+    YOCTO_QT6_ENV_SETUP_SCRIPT = $$system($$MRS_SDK_QT_ROOT/tools/mrs-sdk-manager env YOCTO_QT6_ENV_SETUP_SCRIPT)
+    isEmpty(YOCTO_QT6_ENV_SETUP_SCRIPT) {
+        error("Could not determine Yocto setup script path. Set it with: mrs-sdk-manager env -w YOCTO_QT6_ENV_SETUP_SCRIPT=/path/to/setup-script")
+    }
+    # Source the setup script and extract required variables.
+    OECORE_TARGET_SYSROOT = $$system(. "$$YOCTO_QT6_ENV_SETUP_SCRIPT" && printf "%s" "$OECORE_TARGET_SYSROOT")
+    OECORE_NATIVE_SYSROOT = $$system(. "$$YOCTO_QT6_ENV_SETUP_SCRIPT" && printf "%s" "$OECORE_NATIVE_SYSROOT")
+}
+
+# Set the expected Qt versions based on device target.
+MRS_SDK_QT_QT_MAJOR_VERSION = 6
+MRS_SDK_QT_EXPECTED_QT_VERSION_NEURALPLEX = 6.8.1
+
+# Set the OS identifier variables.
+MRS_SDK_QT_TARGET_OS = Yocto
+MRS_SDK_QT_OS_YOCTO = TRUE
+MRS_SDK_QT_OS_BUILDROOT = FALSE
+MRS_SDK_QT_OS_DESKTOP = FALSE
+
+# Yocto Qt6 builds target 64-bit ARM devices.
+MRS_SDK_QT_SYSTEM_NAME = Linux
+MRS_SDK_QT_SYSTEM_PROCESSOR = aarch64
+MRS_SDK_QT_CROSSCOMPILING = TRUE
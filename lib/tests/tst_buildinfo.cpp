@@ -0,0 +1,31 @@
+#include "BuildInfo.hpp"
+
+#include <QtTest>
+
+using mrs_sdk::BuildInfo;
+
+class TestBuildInfo : public QObject
+{
+    Q_OBJECT
+
+private slots:
+    void targetMatchesExpectedTarget();
+};
+
+void TestBuildInfo::targetMatchesExpectedTarget()
+{
+#if defined(EXPECTED_DEVICE) && defined(EXPECTED_OS)
+    // The expected values come from the target matrix, not from the defines the library was built with.
+    QCOMPARE(BuildInfo::targetDevice(), QStringLiteral(EXPECTED_DEVICE));
+    QCOMPARE(BuildInfo::targetOs(), QStringLiteral(EXPECTED_OS));
+
+    // Consumers see the same shared definitions, so they must agree with the library as well.
+    QCOMPARE(QString(MRS_SDK_QT_TARGET_DEVICE), QStringLiteral(EXPECTED_DEVICE));
+    QCOMPARE(QString(MRS_SDK_QT_TARGET_OS), QStringLiteral(EXPECTED_OS));
+#else
+    QSKIP("MRS_SDK_QT_TEST_EXPECTED_DEVICE and MRS_SDK_QT_TEST_EXPECTED_OS are not set");
+#endif
+}
+
+QTEST_GUILESS_MAIN(TestBuildInfo)
+#include "tst_buildinfo.moc"
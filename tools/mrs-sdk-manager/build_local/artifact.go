@@ -0,0 +1,217 @@
+package buildlocal
+
+import (
+	"bytes"
+	"debug/elf"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// elfArch is the ELF machine type and class that objects compiled for a
+// processor must have.
+type elfArch struct {
+	machine elf.Machine
+	class   elf.Class
+}
+
+// processorArchs maps the processor of a BuildTarget to its ELF architecture.
+var processorArchs = map[string]elfArch{
+	"arm":     {elf.EM_ARM, elf.ELFCLASS32},
+	"aarch64": {elf.EM_AARCH64, elf.ELFCLASS64},
+	"x86_64":  {elf.EM_X86_64, elf.ELFCLASS64},
+}
+
+// Float ABI flags of the ARM EABI version 5 e_flags field.
+const (
+	efARMABIFloatSoft = 0x200
+	efARMABIFloatHard = 0x400
+)
+
+const (
+	arMagic      = "!<arch>\n"
+	arThinMagic  = "!<thin>\n"
+	arHeaderSize = 60
+)
+
+// arMember is one file stored in a static library.
+type arMember struct {
+	name string
+	data []byte
+}
+
+// verifyArtifact checks that every object in the static library at path was
+// compiled for the target's processor, ELF class and, on ARM, float ABI. A
+// mismatch almost always means the env config points at the wrong compiler.
+func verifyArtifact(path string, target BuildTarget) error {
+	expected, ok := processorArchs[target.Processor]
+	if !ok {
+		return fmt.Errorf("unknown processor %q", target.Processor)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	members, err := readArchive(data)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	objects := 0
+	for _, member := range members {
+		if !bytes.HasPrefix(member.data, []byte(elf.ELFMAG)) {
+			return fmt.Errorf("%s: member %s is not an ELF object", path, member.name)
+		}
+		file, err := elf.NewFile(bytes.NewReader(member.data))
+		if err != nil {
+			return fmt.Errorf("%s: failed to parse member %s: %w", path, member.name, err)
+		}
+		objects++
+
+		if file.Machine != expected.machine || file.Class != expected.class {
+			return fmt.Errorf("%s contains %s objects (member %s), but %s requires %s objects; check the compiler configured in %s",
+				path, describeArch(file.Machine, file.Class), member.name, target.BuildDir(),
+				describeArch(expected.machine, expected.class), target.Env.CXXCompiler)
+		}
+
+		if file.Machine == elf.EM_ARM {
+			floatABI, flags := "", elfFlags(member.data, file)
+			switch {
+			case flags&efARMABIFloatHard != 0:
+				floatABI = "hard"
+			case flags&efARMABIFloatSoft != 0:
+				floatABI = "soft"
+			}
+			// Objects without float ABI flags predate EABI version 5 and
+			// cannot be checked.
+			if floatABI != "" && floatABI != target.FloatABI {
+				return fmt.Errorf("%s contains %s-float objects (member %s), but %s requires the %s-float ABI; check the compiler flags for %s",
+					path, floatABI, member.name, target.BuildDir(), target.FloatABI, target.Env.CXXCompiler)
+			}
+		}
+	}
+
+	if objects == 0 {
+		return fmt.Errorf("%s contains no object files", path)
+	}
+	return nil
+}
+
+// elfFlags returns the e_flags field of the ELF header, which debug/elf does
+// not expose.
+func elfFlags(data []byte, file *elf.File) uint32 {
+	offset := 36 // ELF32 e_flags
+	if file.Class == elf.ELFCLASS64 {
+		offset = 48
+	}
+	if len(data) < offset+4 {
+		return 0
+	}
+	return file.ByteOrder.Uint32(data[offset:])
+}
+
+// describeArch names an ELF architecture after the matching processor when
+// there is one, e.g. "arm (ELFCLASS32)".
+func describeArch(machine elf.Machine, class elf.Class) string {
+	name := machine.String()
+	for processor, arch := range processorArchs {
+		if arch.machine == machine {
+			name = processor
+		}
+	}
+	return fmt.Sprintf("%s (%s)", name, class)
+}
+
+// readArchive returns the object files of a static library in the common ar
+// format used by GNU and BSD ar. Symbol tables are skipped.
+func readArchive(data []byte) ([]arMember, error) {
+	if bytes.HasPrefix(data, []byte(arThinMagic)) {
+		return nil, fmt.Errorf("thin archives are not supported")
+	}
+	if !bytes.HasPrefix(data, []byte(arMagic)) {
+		return nil, fmt.Errorf("not a static library")
+	}
+
+	var members []arMember
+	var longNames []byte
+	offset := len(arMagic)
+	for offset < len(data) {
+		if len(data)-offset < arHeaderSize {
+			return nil, fmt.Errorf("truncated member header at offset %d", offset)
+		}
+		header := data[offset : offset+arHeaderSize]
+		if string(header[58:60]) != "`\n" {
+			return nil, fmt.Errorf("invalid member header at offset %d", offset)
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(string(header[48:58])))
+		if err != nil || size < 0 || size > len(data)-offset-arHeaderSize {
+			return nil, fmt.Errorf("invalid member size at offset %d", offset)
+		}
+
+		name := strings.TrimRight(string(header[0:16]), " ")
+		body := data[offset+arHeaderSize : offset+arHeaderSize+size]
+		// Members are aligned to even offsets.
+		offset += arHeaderSize + size + size%2
+
+		switch {
+		case name == "/" || name == "/SYM64/" || strings.HasPrefix(name, "__.SYMDEF"):
+			continue
+		case name == "//":
+			longNames = body
+			continue
+		case strings.HasPrefix(name, "#1/"):
+			// BSD ar stores long names at the start of the member data.
+			nameLen, err := strconv.Atoi(name[3:])
+			if err != nil || nameLen > len(body) {
+				return nil, fmt.Errorf("invalid member name %q", name)
+			}
+			name, body = strings.TrimRight(string(body[:nameLen]), "\x00"), body[nameLen:]
+			if strings.HasPrefix(name, "__.SYMDEF") {
+				continue
+			}
+		case strings.HasPrefix(name, "/"):
+			// GNU ar stores long names in the "//" member.
+			start, err := strconv.Atoi(name[1:])
+			if err != nil || start >= len(longNames) {
+				return nil, fmt.Errorf("invalid member name %q", name)
+			}
+			name = string(longNames[start:])
+			if end := strings.Index(name, "/\n"); end >= 0 {
+				name = name[:end]
+			}
+		default:
+			name = strings.TrimSuffix(name, "/")
+		}
+
+		members = append(members, arMember{name: name, data: body})
+	}
+
+	return members, nil
+}
+
+// validFloatABIs are the float ABIs an ARM target can require.
+var validFloatABIs = []string{"hard", "soft"}
+
+// validateArch checks that the artifacts of a matrix entry can be verified.
+func (b *BuildTarget) validateArch() error {
+	if _, ok := processorArchs[b.Processor]; !ok {
+		var processors []string
+		for processor := range processorArchs {
+			processors = append(processors, processor)
+		}
+		return fmt.Errorf("invalid processor %q (expected one of: %s)", b.Processor, strings.Join(uniqueSorted(processors), ", "))
+	}
+
+	if b.Processor == "arm" {
+		if !slices.Contains(validFloatABIs, b.FloatABI) {
+			return fmt.Errorf("float_abi must be one of %s for arm targets", strings.Join(validFloatABIs, ", "))
+		}
+	} else if b.FloatABI != "" {
+		return fmt.Errorf("float_abi is only supported for arm targets")
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package buildlocal
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestVerifyArtifactAcceptsMatchingObjects verifies that libraries built for
+// every target in the matrix pass the architecture check, including archives
+// with GNU long member names.
+func TestVerifyArtifactAcceptsMatchingObjects(t *testing.T) {
+	dir := t.TempDir()
+	for _, target := range testTargetMatrix(t).AllBuildTargets() {
+		path := filepath.Join(dir, target.BuildDir()+".a")
+		writeTestFile(t, path, string(testArtifact(target)))
+		if err := verifyArtifact(path, target); err != nil {
+			t.Fatalf("expected %s objects to be accepted, got error: %v", target.BuildDir(), err)
+		}
+	}
+}
+
+// TestVerifyArtifactRejectsMismatches verifies that host objects, objects
+// with the wrong float ABI, and archives without objects are rejected with
+// an explanation of what to check.
+func TestVerifyArtifactRejectsMismatches(t *testing.T) {
+	matrix := testTargetMatrix(t)
+	targets, err := matrix.SelectBuildTargets([]string{"debug"}, TargetFilter{Targets: []string{"mconn-yocto-qt5", "desktop-desktop-qt6"}})
+	if err != nil {
+		t.Fatalf("expected selection to succeed, got error: %v", err)
+	}
+	mconn, desktop := targets[0], targets[1]
+
+	softFloat := testArchive(map[string][]byte{"gpio.cpp.o": testELFObject(elf.EM_ARM, elf.ELFCLASS32, efARMABIFloatSoft)})
+	testCases := []struct {
+		name     string
+		archive  []byte
+		expected string
+	}{
+		{"host objects", testArtifact(desktop), "contains x86_64 (ELFCLASS64) objects (member a_member_name_longer_than_16.cpp.o), but mconn-yocto-qt5-debug requires arm (ELFCLASS32) objects; check the compiler configured in YOCTO_QT5_CXX_COMPILER"},
+		{"float abi", softFloat, "contains soft-float objects (member gpio.cpp.o), but mconn-yocto-qt5-debug requires the hard-float ABI"},
+		{"not elf", testArchive(map[string][]byte{"notes.txt": []byte("hello")}), "member notes.txt is not an ELF object"},
+		{"empty", testArchive(nil), "contains no object files"},
+		{"not an archive", []byte("libmrs-sdk-qt"), "not a static library"},
+	}
+
+	path := filepath.Join(t.TempDir(), "libmrs-sdk-qt.a")
+	for _, testCase := range testCases {
+		writeTestFile(t, path, string(testCase.archive))
+		err := verifyArtifact(path, mconn)
+		if err == nil || !strings.Contains(err.Error(), testCase.expected) {
+			t.Fatalf("%s: expected error containing %q, got %v", testCase.name, testCase.expected, err)
+		}
+	}
+}
+
+// TestInstallBuildsRefusesWrongArchitecture verifies that a library built
+// by a misconfigured compiler is never installed for a cross target.
+func TestInstallBuildsRefusesWrongArchitecture(t *testing.T) {
+	repoRoot := t.TempDir()
+	t.Setenv("MRS_SDK_QT_ROOT", filepath.Join(t.TempDir(), "sdk"))
+	initTestRepo(t, repoRoot)
+	createFakeSDKRepo(t, repoRoot)
+
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{Targets: []string{"fusion-buildroot-qt5", "desktop-desktop-qt5"}})
+	if err != nil {
+		t.Fatalf("expected selection to succeed, got error: %v", err)
+	}
+	writeTestFile(t, filepath.Join(repoRoot, "build", targets[0].BuildDir(), "artifacts", "libmrs-sdk-qt.a"), string(testArtifact(targets[1])))
+
+	err = InstallBuilds(repoRoot, targets)
+	if err == nil || !strings.Contains(err.Error(), "refusing to install fusion-buildroot-qt5-debug") {
+		t.Fatalf("expected the x86_64 library to be refused, got %v", err)
+	}
+}
+
+// testArtifact returns a static library with objects for the target's
+// processor and float ABI.
+func testArtifact(target BuildTarget) []byte {
+	arch := processorArchs[target.Processor]
+	var flags uint32
+	switch target.FloatABI {
+	case "hard":
+		flags = efARMABIFloatHard
+	case "soft":
+		flags = efARMABIFloatSoft
+	}
+
+	object := testELFObject(arch.machine, arch.class, flags)
+	return testArchive(map[string][]byte{
+		"buildinfo.cpp.o":                    object,
+		"a_member_name_longer_than_16.cpp.o": object,
+	})
+}
+
+// testArchive returns a GNU ar archive with the given members, sorted by
+// name, after a symbol table.
+func testArchive(members map[string][]byte) []byte {
+	var names []string
+	for name := range members {
+		names = append(names, name)
+	}
+	names = uniqueSorted(names)
+
+	var buf, longNames bytes.Buffer
+	buf.WriteString(arMagic)
+	writeMember := func(name string, data []byte) {
+		fmt.Fprintf(&buf, "%-16s%-12s%-6s%-6s%-8s%-10d`\n", name, "0", "0", "0", "644", len(data))
+		buf.Write(data)
+		if len(data)%2 == 1 {
+			buf.WriteByte('\n')
+		}
+	}
+
+	writeMember("/", make([]byte, 4))
+	for _, name := range names {
+		if len(name) >= 16 {
+			longNames.WriteString(name + "/\n")
+		}
+	}
+	if longNames.Len() > 0 {
+		writeMember("//", longNames.Bytes())
+	}
+	for _, name := range names {
+		if len(name) >= 16 {
+			writeMember(fmt.Sprintf("/%d", strings.Index(longNames.String(), name+"/\n")), members[name])
+		} else {
+			writeMember(name+"/", members[name])
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// testELFObject returns the header of a little-endian relocatable object
+// without any sections.
+func testELFObject(machine elf.Machine, class elf.Class, flags uint32) []byte {
+	header := make([]byte, 64)
+	copy(header, elf.ELFMAG)
+	header[elf.EI_CLASS] = byte(class)
+	header[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	header[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	le := binary.LittleEndian
+	le.PutUint16(header[16:], uint16(elf.ET_REL))
+	le.PutUint16(header[18:], uint16(machine))
+	le.PutUint32(header[20:], uint32(elf.EV_CURRENT))
+	if class == elf.ELFCLASS32 {
+		le.PutUint32(header[36:], flags)
+		le.PutUint16(header[40:], 52)
+		return header[:52]
+	}
+	le.PutUint32(header[48:], flags)
+	le.PutUint16(header[52:], 64)
+	return header
+}
@@ -0,0 +1,60 @@
+package buildlocal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"mrs-sdk-manager/utils"
+)
+
+// buildInfoFileName is the file inside build/<target>/ that records which
+// sources the library artifact was built from.
+const buildInfoFileName = "mrs-sdk-build-info.json"
+
+// buildInfo identifies the sources of a library build, so that `install` can
+// tell whether existing artifacts belong to the checked out version.
+type buildInfo struct {
+	Version string `json:"version"` // As reported by utils.ResolveSDKVersion
+	Commit  string `json:"commit"`  // Empty outside a Git checkout
+	Dirty   bool   `json:"dirty"`   // lib/ had uncommitted changes
+}
+
+// resolveBuildInfo describes the sources currently checked out at sdkRoot.
+func resolveBuildInfo(sdkRoot string) buildInfo {
+	info := buildInfo{Version: utils.ResolveSDKVersion(sdkRoot)}
+
+	output, err := exec.Command("git", "-C", sdkRoot, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return info
+	}
+	info.Commit = strings.TrimSpace(string(output))
+
+	output, err = exec.Command("git", "-C", sdkRoot, "status", "--porcelain", "--untracked-files=no", "--", "lib").Output()
+	info.Dirty = err != nil || len(strings.TrimSpace(string(output))) > 0
+
+	return info
+}
+
+func writeBuildInfo(buildDir string, info buildInfo) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(buildDir, buildInfoFileName), append(data, '\n'), 0644)
+}
+
+func readBuildInfo(buildDir string) (buildInfo, error) {
+	var info buildInfo
+	data, err := os.ReadFile(filepath.Join(buildDir, buildInfoFileName))
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, fmt.Errorf("failed to parse %s: %w", buildInfoFileName, err)
+	}
+	return info, nil
+}
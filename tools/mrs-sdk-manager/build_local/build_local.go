@@ -4,88 +4,204 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"mrs-sdk-manager/env"
 	"mrs-sdk-manager/utils"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"syscall"
 
 	"github.com/fatih/color"
 )
 
 // BuildConfig represents a single build configuration
 type BuildConfig struct {
-	Target   BuildTarget
-	CmakeCmd []string
+	Target         BuildTarget
+	Project        string     // Demo project built for Target; empty for the SDK library
+	BuildDir       string     // Absolute binary directory, which also holds the build log
+	ConfigureCmd   []string   // Configure argv (cmake or qmake)
+	BuildCmd       []string   // Build argv, without the job limit
+	JobsFlag       string     // Flag that passes the job limit to BuildCmd; defaults to --parallel
+	TestCmd        []string   // Optional argv run after a successful build
+	EnvSetupScript string     // Script whose environment both steps run in; empty to inherit ours
+	Env            []string   // Extra KEY=VALUE entries for both steps
+	Artifact       string     // Static library whose architecture is verified after the build
+	Fingerprint    string     // Hash of the inputs to this build; empty to always build
+	BuildInfo      *buildInfo // Sources recorded in BuildDir after a successful build; nil to skip
+	Reconfigure    string     // Why the existing CMake cache is discarded before configuring; empty to keep it
+	UpToDate       bool       // The last successful build used the same fingerprint
 }
 
-// Run executes the requested local build scope, optionally installing the
-// compiled SDK libraries before demo builds consume them.
-func Run(scope BuildScope, installFlag bool) error {
-	// Get the current working directory (SDK root)
-	sdkRoot, err := os.Getwd()
+// Options holds the command-line settings for a build-local invocation.
+type Options struct {
+	Install    bool
+	BuildTypes []string
+	Filter     TargetFilter
+	Force      bool // Rebuild targets even when they are up to date
+	KeepGoing  bool // Build every target even after one has failed
+	DryRun     bool // Print the build and install plan without running it
+
+	Jobs            int // Total compile jobs across all targets; 0 uses every CPU
+	ParallelTargets int // Targets built concurrently; 0 derives it from Jobs
+
+	Output OutputMode
+}
+
+// Run executes the requested local build scope in the checkout at sdkRoot,
+// optionally installing the compiled SDK libraries before demo builds consume
+// them.
+func Run(sdkRoot string, scope BuildScope, opts Options) error {
+	// JSON events own stdout so that CI can parse it; everything meant for
+	// humans goes to stderr instead.
+	opts.Output = opts.Output.resolve(os.Stdout)
+	if opts.Output == OutputJSON {
+		color.Output = color.Error
+	}
+
+	// Ctrl-C and SIGTERM cancel the running builds instead of killing this
+	// process and orphaning them.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Only the first signal is handled; a second Ctrl-C kills this process
+	// while the builds are still being stopped.
+	context.AfterFunc(ctx, stop)
+
+	// The demos have to be built against the libraries that were just built,
+	// not whatever happens to be installed already.
+	if scope == BuildScopeAll && !opts.Install {
+		return fmt.Errorf("--install must be passed when TARGET is all")
+	}
+
+	matrix, err := LoadTargetMatrix(sdkRoot)
 	if err != nil {
-		return fmt.Errorf("failed to get working directory: %w", err)
+		return err
 	}
 
-	// Verify we're in the mrs-sdk-qt repository
-	if err := verifyRepoRoot(sdkRoot); err != nil {
+	targets, err := matrix.SelectBuildTargets(opts.BuildTypes, opts.Filter)
+	if err != nil {
 		return err
 	}
 
-	envConfig, err := readBuildEnvironment(scope)
+	envConfig, err := readBuildEnvironment(scope, targets)
 	if err != nil {
 		return err
 	}
 
+	// A dry run must not execute anything, not even the version checks.
+	if opts.DryRun {
+		tools, missing := locateBuildTools(envConfig, scope, targets)
+		return printDryRun(color.Output, sdkRoot, envConfig, tools, missing, scope, targets, opts)
+	}
+
+	tools, err := resolveBuildTools(envConfig, scope, targets)
+	if err != nil {
+		return err
+	}
+	utils.PrintTaskStart("Using build tools")
+	tools.print(color.Output)
+
+	// The libraries and the demos are reported in one status table. With
+	// --install, the libraries are installed between the two stages, so that
+	// the demos are built against them.
+	var stages []buildStage
 	if scope.IncludesLibs() {
-		if err := buildLibraries(sdkRoot, envConfig); err != nil {
+		stage, err := planLibraryStage(sdkRoot, envConfig, tools, targets, opts)
+		if err != nil {
 			return err
 		}
+		stages = append(stages, stage)
+	}
+	if scope.IncludesDemos() {
+		stage, err := planDemoStage(sdkRoot, envConfig, tools, targets, opts, scope.IncludesLibs())
+		if err != nil {
+			return err
+		}
+		stages = append(stages, stage)
+	}
+	if len(stages) > 0 {
+		reporter := newProgressReporter(opts.Output, os.Stdout)
+		if err := runBuildStages(ctx, sdkRoot, stages, opts.KeepGoing, reporter); err != nil {
+			return err
+		}
+		utils.PrintSuccess("All builds completed successfully")
+	}
 
-		if installFlag {
-			if err := InstallBuilds(sdkRoot); err != nil {
-				return err
-			}
+	if scope == BuildScopeLibs && opts.Install {
+		if err := InstallBuilds(sdkRoot, targets); err != nil {
+			return err
 		}
 	}
 
-	if scope.IncludesDemos() {
-		if installFlag {
-			if err := InstallDemoSources(sdkRoot); err != nil {
-				return err
-			}
-		} else {
-			return fmt.Errorf("--install must be passed when TARGET is all or demos")
+	if scope.IncludesTests() {
+		if err := runTests(ctx, sdkRoot, envConfig, tools, targets, opts); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-// buildLibraries builds the SDK library from source for all supported
-// configurations.
-func buildLibraries(sdkRoot string, envConfig map[string]string) error {
+// planLibraryStage plans the builds of the SDK library for the selected
+// configurations. Targets whose inputs have not changed since their last
+// successful build are skipped unless opts.Force is set.
+func planLibraryStage(sdkRoot string, envConfig map[string]string, tools buildTools, targets []BuildTarget, opts Options) (buildStage, error) {
 	utils.PrintTaskStart("Building MRS SDK libraries from source...")
-	configs := getBuildConfigs(sdkRoot, envConfig)
-	if err := runAllBuilds(sdkRoot, configs); err != nil {
-		return err
+	configs, numOutdated, err := planLibraryBuilds(sdkRoot, envConfig, tools, targets, opts.Force)
+	if err != nil {
+		return buildStage{}, err
 	}
 
-	utils.PrintSuccess("All builds completed successfully")
-	return nil
+	// Skipped targets were built from identical sources, so their artifacts
+	// belong to the current commit as well.
+	for _, config := range configs {
+		if config.UpToDate {
+			if err := writeBuildInfo(config.BuildDir, *config.BuildInfo); err != nil {
+				return buildStage{}, fmt.Errorf("failed to write build info: %w", err)
+			}
+		}
+	}
+
+	concurrency, jobsPerTarget := jobBudget(opts.Jobs, opts.ParallelTargets, numOutdated)
+	color.White("Building up to %d target(s) at a time with %d job(s) each", concurrency, jobsPerTarget)
+	return buildStage{configs: configs, concurrency: concurrency, jobsPerTarget: jobsPerTarget}, nil
 }
 
-func readBuildEnvironment(scope BuildScope) (map[string]string, error) {
+// planLibraryBuilds returns the library build configurations with their
+// fingerprints, and how many of them are not up to date.
+func planLibraryBuilds(sdkRoot string, envConfig map[string]string, tools buildTools, targets []BuildTarget, force bool) ([]BuildConfig, int, error) {
+	configs := getBuildConfigs(sdkRoot, envConfig, tools, targets)
+
+	sourceDigest, err := hashTrackedSources(sdkRoot)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fingerprint SDK sources: %w", err)
+	}
+	info := resolveBuildInfo(sdkRoot)
+	numOutdated := 0
+	for i := range configs {
+		configs[i].BuildInfo = &info
+		configs[i].Fingerprint = targetFingerprint(sourceDigest, configs[i], envConfig)
+		configs[i].UpToDate = !force && isUpToDate(sdkRoot, configs[i])
+		if !configs[i].UpToDate {
+			numOutdated++
+		}
+	}
+
+	return configs, numOutdated, nil
+}
+
+func readBuildEnvironment(scope BuildScope, targets []BuildTarget) (map[string]string, error) {
 	// Read environment config
 	envConfig, err := env.ReadAll()
 	if err != nil {
 		return nil, fmt.Errorf("failed to read environment config: %w", err)
 	}
 
-	requiredKeys := requiredEnvVarsForScope(scope)
+	requiredKeys := requiredEnvVarsForScope(scope, targets)
 	var missingKeys []string
 	for _, k := range requiredKeys {
 		if envConfig[k.Key] == "" {
@@ -99,232 +215,409 @@ func readBuildEnvironment(scope BuildScope) (map[string]string, error) {
 	return envConfig, nil
 }
 
-func requiredEnvVarsForScope(scope BuildScope) []env.EnvVar {
-	if scope == BuildScopeDemos {
-		return nil
+// requiredEnvVarsForScope returns the env keys needed to build the selected
+// targets, without duplicates.
+func requiredEnvVarsForScope(scope BuildScope, targets []BuildTarget) []env.EnvVar {
+	// The demos are built with the same kits as the libraries, so every scope
+	// needs the same keys.
+	var required []env.EnvVar
+	for _, target := range targets {
+		for _, v := range requiredEnvVarsForTarget(target) {
+			if !slices.Contains(required, v) {
+				required = append(required, v)
+			}
+		}
 	}
 
-	return []env.EnvVar{
-		env.YOCTO_QT5_SYSROOT,
-		env.YOCTO_QT5_CXX_COMPILER,
-		env.YOCTO_QT5_ENV_SETUP_SCRIPT,
-		env.BUILDROOT_QT5_SYSROOT,
-		env.BUILDROOT_QT5_CXX_COMPILER,
-		env.DESKTOP_CXX_COMPILER,
-		env.DESKTOP_QT5_PREFIX,
-		env.DESKTOP_QT6_PREFIX,
-	}
+	return required
 }
 
-// verifyRepoRoot verifies that we're in the mrs-sdk-qt repository root
-func verifyRepoRoot(sdkRoot string) error {
-	// Get the git repository root
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-	cmd.Dir = sdkRoot
-	output, err := cmd.Output()
-	if err != nil {
-		return fmt.Errorf("not in a git repository")
+// requiredEnvVarsForTarget returns the env keys read by getBuildConfigs for a
+// single target, as named by its matrix entry.
+func requiredEnvVarsForTarget(target BuildTarget) []env.EnvVar {
+	var required []env.EnvVar
+	for _, key := range []string{target.Env.Sysroot, target.Env.CXXCompiler, target.Env.EnvSetupScript, target.Env.QtPrefix} {
+		if v, ok := env.EnvVarsMetadataMap[key]; ok {
+			required = append(required, v)
+		}
 	}
 
-	gitRoot := strings.TrimSpace(string(output))
+	return required
+}
 
-	// Check if we're at the repository root
-	if gitRoot != sdkRoot {
-		return fmt.Errorf("not at repository root (current: %s, root: %s)", sdkRoot, gitRoot)
-	}
+type buildError struct {
+	config BuildConfig
+	err    error
+}
 
-	// Verify it's the mrs-sdk-qt repository by checking remote origin URL
-	cmd = exec.Command("git", "config", "--get", "remote.origin.url")
-	cmd.Dir = sdkRoot
-	output, err = cmd.Output()
-	if err != nil {
-		return fmt.Errorf("failed to get remote origin URL")
+// buildStage is a group of build configurations within one run. A stage only
+// starts once every earlier stage has succeeded, so that the demos can be
+// built against the libraries installed before them.
+type buildStage struct {
+	configs       []BuildConfig
+	concurrency   int
+	jobsPerTarget int
+	// prepare runs before the stage's builds start, e.g. to install what they
+	// consume. It may be nil.
+	prepare func() error
+}
+
+// runAllBuilds builds every configuration that is not up to date, running at
+// most concurrency builds at once with jobsPerTarget compile jobs each.
+// Every state change is passed to reporter. Unless keepGoing is set, the
+// first failure cancels every other build, including the running ones.
+// Cancelling parentCtx, e.g. on Ctrl-C, does the same.
+func runAllBuilds(parentCtx context.Context, sdkRoot string, configs []BuildConfig, concurrency, jobsPerTarget int, keepGoing bool, reporter progressReporter) error {
+	return runBuildStages(parentCtx, sdkRoot, []buildStage{{configs: configs, concurrency: concurrency, jobsPerTarget: jobsPerTarget}}, keepGoing, reporter)
+}
+
+// runBuildStages runs the stages one after another like runAllBuilds, with
+// the configurations of every stage reported as one run. Once a stage has
+// failed, the later stages are cancelled without being prepared. The output
+// of the prepare steps is held back until the reporter has finished, so that
+// it does not break the status table.
+func runBuildStages(parentCtx context.Context, sdkRoot string, stages []buildStage, keepGoing bool, reporter progressReporter) error {
+	var configs []BuildConfig
+	for _, stage := range stages {
+		configs = append(configs, stage.configs...)
 	}
 
-	remoteURL := strings.TrimSpace(string(output))
-	repoName := filepath.Base(strings.TrimSuffix(remoteURL, ".git"))
+	// A cache from a different compiler or kit would silently be reused by
+	// CMake, so those targets are configured from scratch.
+	for i := range configs {
+		if configs[i].UpToDate {
+			continue
+		}
+		reason, err := staleCacheReason(configs[i])
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			configs[i].Reconfigure = reason
+			color.Yellow("Reconfiguring %s from scratch: %s", configs[i].label(), reason)
+		}
+	}
 
-	if repoName != "mrs-sdk-qt" {
-		return fmt.Errorf("not in the mrs-sdk-qt repository (found: %s)", repoName)
+	var prepareOutput bytes.Buffer
+	failures, prepareErr := runReportedStages(parentCtx, sdkRoot, stages, configs, keepGoing, reporter, &prepareOutput)
+	if _, err := color.Output.Write(prepareOutput.Bytes()); err != nil {
+		return err
 	}
 
+	if prepareErr != nil {
+		return prepareErr
+	}
+	if err := summarizeFailures(failures, len(configs)); err != nil {
+		return err
+	}
+	if parentCtx.Err() != nil {
+		return fmt.Errorf("build interrupted")
+	}
 	return nil
 }
 
-type buildError struct {
-	config BuildConfig
-	err    error
-}
-
-func runAllBuilds(sdkRoot string, configs []BuildConfig) error {
-	const maxConcurrency = 4
-	semaphore := make(chan struct{}, maxConcurrency)
-	var wg sync.WaitGroup
+// runReportedStages builds configs, the configurations of every stage in
+// order, between reporter.begin and reporter.finish. It returns the failures
+// indexed like configs, and the error of a failed prepare step.
+func runReportedStages(parentCtx context.Context, sdkRoot string, stages []buildStage, configs []BuildConfig, keepGoing bool, reporter progressReporter, prepareOutput io.Writer) ([]*buildError, error) {
 	var mu sync.Mutex
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(parentCtx)
 	defer cancel()
 
-	var firstErr buildError
+	// Failures are indexed like configs so that the summary follows the
+	// order of the status output rather than completion order.
+	failures := make([]*buildError, len(configs))
 
-	numConfigs := len(configs)
+	reporter.begin(configs)
+	// Always leave the terminal in a usable state, even when interrupted.
+	defer reporter.finish()
 
-	// Pre-calculate all status messages and find max length for column alignment
-	statusMsgs := make([]string, numConfigs)
-	maxMsgLen := 0
-	for i, config := range configs {
-		statusMsgs[i] = fmt.Sprintf("[%d/%d] Building SDK lib for %s", i+1, numConfigs, config.Target.BuildDir())
-		if len(statusMsgs[i]) > maxMsgLen {
-			maxMsgLen = len(statusMsgs[i])
-		}
-	}
+	offset := 0
+	for _, stage := range stages {
+		first := offset
+		offset += len(stage.configs)
 
-	// Print initial status lines with padding
-	for i := range configs {
-		padding := strings.Repeat(" ", maxMsgLen-len(statusMsgs[i])+3)
-		fmt.Println(color.WhiteString(statusMsgs[i]) + padding + " " + color.YellowString("Pending"))
-	}
-
-	for i, config := range configs {
-		wg.Add(1)
-		go func(i int, config BuildConfig) {
-			defer wg.Done()
+		if ctx.Err() == nil && slices.ContainsFunc(failures[:first], func(failure *buildError) bool { return failure != nil }) {
+			// With keepGoing, the other builds of a stage still finish, but
+			// the next stage depends on all of them.
+			cancel()
+		}
+		if ctx.Err() == nil && stage.prepare != nil {
+			output := color.Output
+			color.Output = prepareOutput
+			err := stage.prepare()
+			color.Output = output
+			if err != nil {
+				cancel()
+				cancelStages(configs[first:], first, reporter)
+				return failures, err
+			}
+		}
 
-			// Check if build was cancelled
-			if ctx.Err() != nil {
-				return
+		semaphore := make(chan struct{}, stage.concurrency)
+		var wg sync.WaitGroup
+		for i := first; i < offset; i++ {
+			config := configs[i]
+			// Up-to-date targets were already reported by begin.
+			if config.UpToDate {
+				continue
 			}
 
-			// Acquire semaphore slot
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+			wg.Add(1)
+			go func(i int, config BuildConfig) {
+				defer wg.Done()
 
-			// Check again after acquiring semaphore.
-			// It's possible cancel() was called during the time we were waiting.
-			if ctx.Err() != nil {
-				return
-			}
+				// Check if build was cancelled
+				if ctx.Err() != nil {
+					reporter.update(i, stateCancelled, nil)
+					return
+				}
 
-			// This function handles updating the outputted status of a build.
-			updateStatusFunc := func(status string) {
-				// Figure out how many lines to move.
-				// The cursor is always at the bottom to start.
-				linesToMove := numConfigs - i
-				padding := strings.Repeat(" ", maxMsgLen-len(statusMsgs[i])+3)
-				// Move up to the line for this build using ANSI code.
-				fmt.Printf("\033[%dA", linesToMove)
-				// Clear the line using ANSI code.
-				fmt.Print("\r" + "\033[K")
-				// Print the new status line.
-				fmt.Println(color.WhiteString(statusMsgs[i]) + padding + " " + status)
-				// Move the cursor back down to the bottom using ANSI code.
-				fmt.Printf("\033[%dB", linesToMove)
-			}
+				// Acquire semaphore slot
+				semaphore <- struct{}{}
+				defer func() { <-semaphore }()
 
-			// Run the build.
-			if err := runBuild(sdkRoot, config); err != nil {
-				// If an error occurs, and it's the first build to error out,
-				// then we need to save it and cancel all the other builds.
-				// The mutex is locked immediately to prevent a race for setting the error and cancelling.
-				mu.Lock()
-				if firstErr.err == nil {
-					firstErr.err = err
-					firstErr.config = config
-					cancel()
+				// Check again after acquiring semaphore.
+				// It's possible cancel() was called during the time we were waiting.
+				if ctx.Err() != nil {
+					reporter.update(i, stateCancelled, nil)
+					return
 				}
-				updateStatusFunc(color.RedString("✗ Failed"))
-				mu.Unlock()
-			} else {
-				mu.Lock()
-				updateStatusFunc(color.GreenString("✓ Success"))
-				mu.Unlock()
-			}
-		}(i, config)
+
+				reporter.update(i, stateBuilding, nil)
+
+				// Run the build.
+				if err := runBuild(ctx, sdkRoot, config, stage.jobsPerTarget); err != nil {
+					// A build that was killed because of another failure or an
+					// interrupt is not a failure of its own.
+					if ctx.Err() != nil {
+						reporter.update(i, stateCancelled, nil)
+						return
+					}
+
+					// Record the failure, and unless we were asked to keep going,
+					// cancel all the builds that have not started yet.
+					// The mutex is locked immediately to prevent a race for recording the error and cancelling.
+					mu.Lock()
+					failures[i] = &buildError{config: config, err: err}
+					if !keepGoing {
+						cancel()
+					}
+					mu.Unlock()
+					reporter.update(i, stateFailed, err)
+				} else {
+					reporter.update(i, stateSuccess, nil)
+				}
+			}(i, config)
+		}
+		wg.Wait()
 	}
 
-	wg.Wait()
+	return failures, nil
+}
 
-	// Display first error if one occurred.
-	if firstErr.err != nil {
-		utils.PrintError(fmt.Sprintf("Build Error in %s:", firstErr.config.Target.BuildDir()), firstErr.err.Error())
-		return fmt.Errorf("build failed for %s", firstErr.config.Target.BuildDir())
+// cancelStages reports every configuration that is not up to date as
+// cancelled. first is the index of configs[0] in the run.
+func cancelStages(configs []BuildConfig, first int, reporter progressReporter) {
+	for i, config := range configs {
+		if !config.UpToDate {
+			reporter.update(first+i, stateCancelled, nil)
+		}
 	}
-
-	return nil
 }
 
-// runBuild executes the CMake configure and build steps for a configuration
-func runBuild(sdkRoot string, config BuildConfig) error {
-	// Create the build command.
-	// Structure: [env-setup &&] cmake configure && cmake build
-	// The build directory is the value of -B flag (4th index in the cmake command array).
-	buildDirArg := config.CmakeCmd[4]
-	fullCmd := fmt.Sprintf("%s && /usr/bin/cmake --build %s --target all",
-		strings.Join(config.CmakeCmd, " "),
-		buildDirArg,
-	)
+// summarizeFailures prints every recorded failure, grouped by target, and
+// returns an error naming the failed targets.
+func summarizeFailures(failures []*buildError, numConfigs int) error {
+	var failed []string
+	for _, failure := range failures {
+		if failure != nil {
+			failed = append(failed, failure.config.label())
+		}
+	}
 
-	// Build!!
-	cmd := exec.Command("/bin/bash", "-c", fullCmd)
-	// Save all output so that we can use it later.
-	var outputBuf bytes.Buffer
-	cmd.Stdout = &outputBuf
-	cmd.Stderr = &outputBuf
-	cmd.Dir = sdkRoot
+	if len(failed) == 0 {
+		return nil
+	}
+	if len(failed) > 1 {
+		utils.PrintError(fmt.Sprintf("%d of %d targets failed:", len(failed), numConfigs), strings.Join(failed, "\n"))
+	}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("%s", outputBuf.String())
+	for _, failure := range failures {
+		if failure != nil {
+			utils.PrintError(fmt.Sprintf("Build Error in %s:", failure.config.label()), failure.err.Error())
+		}
 	}
-	return nil
+
+	return fmt.Errorf("build failed for %s", strings.Join(failed, ", "))
 }
 
-// getBuildConfigs returns all build configurations
-func getBuildConfigs(sdkRoot string, envConfig map[string]string) []BuildConfig {
-	cmakeCmdBuilder := func(b BuildTarget) []string {
-		cmd := []string{
-			"/usr/bin/cmake",
-			"-S", filepath.Join(sdkRoot, "lib"),
-			"-B", filepath.Join(sdkRoot, "build", b.BuildDir()),
-			"-DCMAKE_GENERATOR:STRING=Ninja",
+// maxExcerptLines limits how much of a failed build's log is printed inline.
+const maxExcerptLines = 20
+
+// runBuild executes the CMake configure and build steps for a configuration,
+// limiting the build step to the given number of parallel jobs. Cancelling ctx
+// terminates every process started for the build.
+func runBuild(ctx context.Context, sdkRoot string, config BuildConfig, jobs int) error {
+	// Drop any previous stamp first so that an interrupted or failed build is
+	// never reported as up to date.
+	stampPath := filepath.Join(config.BuildDir, stampFileName)
+	if err := os.Remove(stampPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove build stamp: %w", err)
+	}
+
+	// Stream all output to the target's build log so that it can be
+	// inspected with `mrs-sdk-manager logs` after the fact.
+	if err := os.MkdirAll(config.BuildDir, 0755); err != nil {
+		return fmt.Errorf("failed to create build directory: %w", err)
+	}
+	logPath := config.logPath()
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return fmt.Errorf("failed to create build log: %w", err)
+	}
+	defer logFile.Close()
+	logWriter := newTimestampWriter(logFile)
+	fmt.Fprintf(logWriter, "Building %s for %s\n", config.subject(), config.Target.BuildDir())
+
+	// buildFailed logs why the build stopped and returns the error to report.
+	buildFailed := func(err error) error {
+		if ctx.Err() != nil {
+			fmt.Fprintln(logWriter, "Build cancelled")
+			return ctx.Err()
 		}
-		switch b.OS {
-		case "yocto":
-			cmd = append(cmd, "-DCMAKE_SYSROOT:PATH="+envConfig["YOCTO_QT5_SYSROOT"],
-				"-DCMAKE_CXX_COMPILER:STRING="+envConfig["YOCTO_QT5_CXX_COMPILER"],
-				"-DCMAKE_CXX_COMPILER_TARGET:STRING=arm-poky-linux-gnueabi",
-				"-DCMAKE_TOOLCHAIN_FILE:STRING="+filepath.Join(sdkRoot, "lib/cmake/mrs-sdk-qt/toolchains/yocto-qt5.cmake"),
-				"-DCMAKE_CXX_FLAGS_INIT:STRING=",
-				"-DCMAKE_C_COMPILER_TARGET:STRING=arm-poky-linux-gnueabi",
-				"-DYOCTO_QT5_ENV_SETUP_SCRIPT:FILEPATH="+envConfig["YOCTO_QT5_ENV_SETUP_SCRIPT"])
-		case "buildroot":
-			cmd = append(cmd, "-DCMAKE_CXX_COMPILER:FILEPATH="+envConfig["BUILDROOT_QT5_CXX_COMPILER"],
-				"-DCMAKE_PREFIX_PATH:PATH="+envConfig["BUILDROOT_QT5_SYSROOT"],
-				"-DCMAKE_TOOLCHAIN_FILE:STRING="+filepath.Join(sdkRoot, "lib/cmake/mrs-sdk-qt/toolchains/buildroot-qt5.cmake"))
-		case "desktop":
-			cmd = append(cmd, "-DCMAKE_CXX_COMPILER:FILEPATH="+envConfig["DESKTOP_CXX_COMPILER"])
-			if b.QtVersion == "qt5" {
-				cmd = append(cmd, "-DCMAKE_PREFIX_PATH:PATH="+envConfig["DESKTOP_QT5_PREFIX"],
-					"-DCMAKE_TOOLCHAIN_FILE:STRING="+filepath.Join(sdkRoot, "lib/cmake/mrs-sdk-qt/toolchains/desktop-qt5.cmake"))
-			} else {
-				cmd = append(cmd, "-DCMAKE_PREFIX_PATH:PATH="+envConfig["DESKTOP_QT6_PREFIX"],
-					"-DCMAKE_TOOLCHAIN_FILE:STRING="+filepath.Join(sdkRoot, "lib/cmake/mrs-sdk-qt/toolchains/desktop-qt6.cmake"))
-			}
-			cmd = append(cmd, "-DCMAKE_CXX_FLAGS_INIT:STRING=-DQT_QML_DEBUG")
+		fmt.Fprintf(logWriter, "Build failed: %v\n", err)
+		return fmt.Errorf("%s\n\nFull log: %s", logExcerpt(logPath, maxExcerptLines), logPath)
+	}
+
+	// Cross-compilation kits only work inside the environment of their setup
+	// script, so capture it once and run every step in it.
+	var environ []string
+	if config.EnvSetupScript != "" {
+		fmt.Fprintf(logWriter, "$ . %s\n", formatCommand([]string{config.EnvSetupScript}))
+		environ, err = captureEnvironment(ctx, config.EnvSetupScript, logWriter)
+		if err != nil {
+			return buildFailed(err)
+		}
+	}
+	if len(config.Env) > 0 {
+		if environ == nil {
+			environ = os.Environ()
 		}
-		cmd = append(cmd, "-DMRS_SDK_QT_ROOT:STRING="+os.Getenv("MRS_SDK_QT_ROOT"),
-			"-DMRS_SDK_QT_TARGET_DEVICE:STRING="+b.Device,
-			"-DCMAKE_BUILD_TYPE:STRING="+b.BuildType)
-		return cmd
+		environ = append(environ, config.Env...)
 	}
 
-	var configs []BuildConfig
-	for _, target := range AllBuildTargets() {
-		config := BuildConfig{
-			Target:   target,
-			CmakeCmd: cmakeCmdBuilder(target),
+	if config.Reconfigure != "" {
+		fmt.Fprintf(logWriter, "Removing the CMake cache: %s\n", config.Reconfigure)
+		if err := removeCMakeCache(config.BuildDir); err != nil {
+			return buildFailed(err)
 		}
-		configs = append(configs, config)
+	}
+
+	// Build!!
+	for _, step := range config.plan(jobs) {
+		fmt.Fprintf(logWriter, "$ %s\n", formatCommand(step.args))
+
+		cmd := exec.CommandContext(ctx, lookPathIn(step.args[0], environ), step.args[1:]...)
+		group := configureProcessGroup(cmd)
+		cmd.Env = environ
+		cmd.Stdout = logWriter
+		cmd.Stderr = logWriter
+		cmd.Dir = sdkRoot
+
+		err := cmd.Run()
+		group.finished()
+		if err != nil {
+			return buildFailed(fmt.Errorf("%s step: %w", step.name, err))
+		}
+	}
+	if config.Artifact != "" {
+		if err := verifyArtifact(config.Artifact, config.Target); err != nil {
+			return buildFailed(err)
+		}
+		fmt.Fprintf(logWriter, "Verified %s objects in %s\n", config.Target.Processor, config.Artifact)
+	}
+	fmt.Fprintln(logWriter, "Build succeeded")
+
+	if config.BuildInfo != nil {
+		if err := writeBuildInfo(config.BuildDir, *config.BuildInfo); err != nil {
+			return fmt.Errorf("failed to write build info: %w", err)
+		}
+	}
+	if config.Fingerprint == "" {
+		return nil
+	}
+	if err := writeStamp(sdkRoot, config); err != nil {
+		return fmt.Errorf("failed to write build stamp: %w", err)
+	}
+	return nil
+}
+
+// getBuildConfigs returns the build configurations for the given targets
+func getBuildConfigs(sdkRoot string, envConfig map[string]string, tools buildTools, targets []BuildTarget) []BuildConfig {
+	var configs []BuildConfig
+	for _, target := range targets {
+		configs = append(configs, newLibBuildConfig(sdkRoot, envConfig, tools, target, filepath.Join(sdkRoot, "build", target.BuildDir())))
 	}
 
 	return configs
 }
+
+// newLibBuildConfig returns the configuration that builds the SDK library for
+// a target in buildDir.
+func newLibBuildConfig(sdkRoot string, envConfig map[string]string, tools buildTools, target BuildTarget, buildDir string) BuildConfig {
+	configureCmd := []string{tools.CMake.Path, "-S", filepath.Join(sdkRoot, "lib"), "-B", buildDir}
+	configureCmd = append(configureCmd, tools.generatorArgs()...)
+	configureCmd = append(configureCmd, "-DCMAKE_TOOLCHAIN_FILE:STRING="+filepath.Join(sdkRoot, "lib/cmake/mrs-sdk-qt/toolchains", target.Toolchain+".cmake"))
+	configureCmd = append(configureCmd, cmakeKitArgs(envConfig, target)...)
+	configureCmd = append(configureCmd, "-DMRS_SDK_QT_ROOT:STRING="+os.Getenv("MRS_SDK_QT_ROOT"),
+		"-DMRS_SDK_QT_TARGET_DEVICE:STRING="+target.Device,
+		"-DCMAKE_BUILD_TYPE:STRING="+target.CMakeBuildType(),
+		// Lets `mrs-sdk-manager compile-commands` set up clangd.
+		"-DCMAKE_EXPORT_COMPILE_COMMANDS:BOOL=ON")
+
+	config := BuildConfig{
+		Target:       target,
+		BuildDir:     buildDir,
+		ConfigureCmd: configureCmd,
+		BuildCmd:     []string{tools.CMake.Path, "--build", buildDir, "--target", "all"},
+		Artifact:     filepath.Join(buildDir, "artifacts", "libmrs-sdk-qt.a"),
+	}
+	if target.OS == "yocto" {
+		config.EnvSetupScript = envConfig[target.Env.EnvSetupScript]
+	}
+	return config
+}
+
+// cmakeKitArgs returns the CMake cache entries that select a target's
+// compiler, sysroot and Qt installation. The SDK library and the CMake demos
+// are configured with the same kit.
+func cmakeKitArgs(envConfig map[string]string, b BuildTarget) []string {
+	var args []string
+	switch b.OS {
+	case "yocto":
+		// The toolchain helper reads the setup script from the same cache
+		// entry the env config uses, e.g. YOCTO_QT5_ENV_SETUP_SCRIPT.
+		args = append(args, "-DCMAKE_SYSROOT:PATH="+envConfig[b.Env.Sysroot],
+			"-DCMAKE_CXX_COMPILER:STRING="+envConfig[b.Env.CXXCompiler],
+			"-DCMAKE_CXX_COMPILER_TARGET:STRING="+b.CompilerTarget,
+			"-DCMAKE_CXX_FLAGS_INIT:STRING=",
+			"-DCMAKE_C_COMPILER_TARGET:STRING="+b.CompilerTarget,
+			"-D"+b.Env.EnvSetupScript+":FILEPATH="+envConfig[b.Env.EnvSetupScript])
+	case "buildroot":
+		args = append(args, "-DCMAKE_CXX_COMPILER:FILEPATH="+envConfig[b.Env.CXXCompiler],
+			"-DCMAKE_PREFIX_PATH:PATH="+envConfig[b.Env.Sysroot])
+	case "desktop":
+		args = append(args, "-DCMAKE_CXX_COMPILER:FILEPATH="+envConfig[b.Env.CXXCompiler],
+			"-DCMAKE_PREFIX_PATH:PATH="+envConfig[b.Env.QtPrefix])
+		// QML debugging must not be enabled in the optimized libraries we ship,
+		// which includes RelWithDebInfo.
+		if b.CMakeBuildType() != "Debug" {
+			args = append(args, "-DCMAKE_CXX_FLAGS_INIT:STRING=")
+		} else {
+			args = append(args, "-DCMAKE_CXX_FLAGS_INIT:STRING=-DQT_QML_DEBUG")
+		}
+	}
+	return args
+}
@@ -2,6 +2,7 @@ package buildlocal
 
 import (
 	"fmt"
+	"io/fs"
 	"mrs-sdk-manager/utils"
 	"os"
 	"os/exec"
@@ -11,8 +12,19 @@ import (
 	"github.com/fatih/color"
 )
 
-// InstallBuilds copies all compiled libraries and configuration files to the SDK installation tree
-func InstallBuilds(sdkRepoRoot string) error {
+// InstallBuilds copies the compiled libraries for the given targets and all
+// configuration files to the SDK installation tree
+func InstallBuilds(sdkRepoRoot string, targets []BuildTarget) error {
+	// Mirror the versioning strategy used by the library CMake build so that a
+	// repo-local `build-local --install` produces an installation tree under the
+	// same version label that the compiled artifacts report internally. The
+	// historical 0.0.0 fallback remains in place for untagged development clones.
+	return installBuildsAs(sdkRepoRoot, utils.ResolveSDKVersion(sdkRepoRoot), targets)
+}
+
+// installBuildsAs installs the compiled libraries for the given targets and
+// all configuration files as the given SDK version.
+func installBuildsAs(sdkRepoRoot, sdkVersion string, targets []BuildTarget) error {
 	// Resolve the installation root from the same environment variable that
 	// consumer projects already use. This keeps tool and SDK installation paths consistent
 	// across local development workflows.
@@ -23,25 +35,26 @@ func InstallBuilds(sdkRepoRoot string) error {
 
 	utils.PrintTaskStart(fmt.Sprintf("Installing SDK in %s...", sdkInstallRoot))
 
-	// Create SDK root directory if it doesn't exist
-	if err := os.MkdirAll(sdkInstallRoot, 0755); err != nil {
-		return fmt.Errorf("failed to create SDK root directory: %w", err)
+	// The include and helper directories are installed in full, so headers
+	// removed from lib/ do not linger. Libraries of other targets are kept.
+	var replaced []string
+	for _, dir := range staticInstallDirs(sdkRepoRoot, "") {
+		replaced = append(replaced, dir.dst)
 	}
 
-	// Mirror the versioning strategy used by the library CMake build so that a
-	// repo-local `build-local --install` produces an installation tree under the
-	// same version label that the compiled artifacts report internally. The
-	// historical 0.0.0 fallback remains in place for untagged development clones.
-	sdkVersion := utils.ResolveSDKVersion(sdkRepoRoot)
-	sdkDevVersionRoot := filepath.Join(sdkInstallRoot, sdkVersion)
-
-	// Install include files and CMake/QMake files (only once)
-	if err := installStaticFiles(sdkRepoRoot, sdkDevVersionRoot); err != nil {
-		return fmt.Errorf("failed to install static files: %w", err)
-	}
+	err = stagedInstall(sdkRepoRoot, sdkInstallRoot, sdkVersion, replaced, targets, func(stageRoot string) error {
+		// Install include files and CMake/QMake files (only once)
+		if err := installStaticFiles(sdkRepoRoot, stageRoot); err != nil {
+			return fmt.Errorf("failed to install static files: %w", err)
+		}
 
-	if err := installAllLibraries(AllBuildTargets(), sdkRepoRoot, sdkDevVersionRoot); err != nil {
-		return fmt.Errorf("failed to install libraries: %w", err)
+		if err := installAllLibraries(targets, sdkRepoRoot, stageRoot); err != nil {
+			return fmt.Errorf("failed to install libraries: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	utils.PrintSuccess("All SDK components installed successfully")
@@ -63,24 +76,38 @@ func InstallDemoSources(sdkRepoRoot string) error {
 
 	utils.PrintTaskStart(fmt.Sprintf("Installing demo sources in %s...", demoInstallRoot))
 
-	if err := copyDirectory(demoSourceRoot, demoInstallRoot); err != nil {
-		return fmt.Errorf("failed to copy demo sources: %w", err)
+	err = stagedInstall(sdkRepoRoot, sdkInstallRoot, sdkVersion, []string{"demos"}, nil, func(stageRoot string) error {
+		if err := copyDirectory(demoSourceRoot, filepath.Join(stageRoot, "demos")); err != nil {
+			return fmt.Errorf("failed to copy demo sources: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	utils.PrintSuccess("All demo sources installed successfully")
 	return nil
 }
 
-// installStaticFiles copies include and configuration files to the SDK installation
-func installStaticFiles(sdkRepoRoot, sdkDevVersionRoot string) error {
+// installDirectory is a directory of the repository that is copied into the
+// SDK installation.
+type installDirectory struct {
+	name string
+	src  string
+	dst  string
+}
 
-	type fileMapping struct {
-		name string
-		src  string
-		dst  string
-	}
+// installFile is a single file copied into the SDK installation.
+type installFile struct {
+	Src string
+	Dst string
+}
 
-	files := []fileMapping{
+// staticInstallDirs returns the include and configuration directories that
+// are installed for every SDK version.
+func staticInstallDirs(sdkRepoRoot, sdkDevVersionRoot string) []installDirectory {
+	return []installDirectory{
 		{
 			name: "includes",
 			src:  filepath.Join(sdkRepoRoot, "lib", "include"),
@@ -97,6 +124,11 @@ func installStaticFiles(sdkRepoRoot, sdkDevVersionRoot string) error {
 			dst:  filepath.Join(sdkDevVersionRoot, "lib", "qmake"),
 		},
 	}
+}
+
+// installStaticFiles copies include and configuration files to the SDK installation
+func installStaticFiles(sdkRepoRoot, sdkDevVersionRoot string) error {
+	files := staticInstallDirs(sdkRepoRoot, sdkDevVersionRoot)
 
 	color.White("Installing static files...")
 
@@ -116,7 +148,7 @@ func installStaticFiles(sdkRepoRoot, sdkDevVersionRoot string) error {
 		s := color.WhiteString(statusMsgs[i])
 		// Pad the message to align the success indicators.
 		padding := strings.Repeat(" ", maxStatusLen-len(statusMsgs[i])+3)
-		fmt.Print(s + padding)
+		fmt.Fprint(color.Output, s+padding)
 		if err := copyDirectory(file.src, file.dst); err != nil {
 			return fmt.Errorf("failed to copy directory: %w", err)
 		}
@@ -144,9 +176,9 @@ func installAllLibraries(installTargets []BuildTarget, sdkRepoRoot, sdkDevVersio
 		s := color.WhiteString(statusMsgs[i])
 		// Pad the message to align the success indicators.
 		padding := strings.Repeat(" ", maxStatusLen-len(statusMsgs[i])+3)
-		fmt.Print(s + padding)
+		fmt.Fprint(color.Output, s+padding)
 		if err := installLibrary(target, sdkRepoRoot, sdkDevVersionRoot); err != nil {
-			fmt.Println()
+			fmt.Fprintln(color.Output)
 			return fmt.Errorf("failed to install %s: %w", target.BuildDir(), err)
 		}
 		color.Green("✓ Success.\n")
@@ -155,10 +187,25 @@ func installAllLibraries(installTargets []BuildTarget, sdkRepoRoot, sdkDevVersio
 	return nil
 }
 
+// libraryInstallFile returns where the compiled library of a target is
+// installed from and to.
+func libraryInstallFile(target BuildTarget, sdkRepoRoot, sdkDevVersionRoot string) installFile {
+	return installFile{
+		Src: filepath.Join(sdkRepoRoot, "build", target.BuildDir(), "artifacts", "libmrs-sdk-qt.a"),
+		Dst: filepath.Join(sdkDevVersionRoot, target.InstLibDir(), "libmrs-sdk-qt.a"),
+	}
+}
+
 // installLibrary copies a compiled library to the appropriate installation location
 func installLibrary(target BuildTarget, sdkRepoRoot, sdkDevVersionRoot string) error {
-	srcLib := filepath.Join(sdkRepoRoot, "build", target.BuildDir(), "artifacts", "libmrs-sdk-qt.a")
-	var dstLibDir = filepath.Join(sdkDevVersionRoot, target.InstTreeDir())
+	lib := libraryInstallFile(target, sdkRepoRoot, sdkDevVersionRoot)
+	dstLibDir := filepath.Dir(lib.Dst)
+
+	// Never install a library built by the wrong compiler, even if it was
+	// built outside of build-local.
+	if err := verifyArtifact(lib.Src, target); err != nil {
+		return fmt.Errorf("refusing to install %s: %w", target.BuildDir(), err)
+	}
 
 	// Create the destination directory
 	if err := os.MkdirAll(dstLibDir, 0755); err != nil {
@@ -166,8 +213,7 @@ func installLibrary(target BuildTarget, sdkRepoRoot, sdkDevVersionRoot string) e
 	}
 
 	// Copy the library file
-	dstLib := filepath.Join(dstLibDir, "libmrs-sdk-qt.a")
-	if err := copyFile(srcLib, dstLib); err != nil {
+	if err := copyFile(lib.Src, lib.Dst); err != nil {
 		return fmt.Errorf("failed to copy library: %w", err)
 	}
 
@@ -183,14 +229,47 @@ func copyFile(src, dst string) error {
 	return os.WriteFile(dst, data, 0644)
 }
 
-// copyDirectory recursively copies a directory from src to dst
+// copyDirectory recursively copies the Git-tracked files of a directory from
+// src to dst
 func copyDirectory(src, dst string) error {
-	trackedFiles, trackedDirs, err := trackedPathsForDirectory(src)
+	files, err := listDirectoryFiles(src, dst)
 	if err != nil {
 		return err
 	}
 
-	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	for _, file := range files {
+		info, err := os.Stat(file.Src)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(file.Dst), 0755); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(file.Src)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(file.Dst, data, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listDirectoryFiles returns the Git-tracked files under src, in walk order,
+// with their destinations under dst.
+func listDirectoryFiles(src, dst string) ([]installFile, error) {
+	trackedFiles, trackedDirs, err := trackedPathsForDirectory(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []installFile
+	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -211,26 +290,25 @@ func copyDirectory(src, dst string) error {
 			}
 		}
 
-		dstPath := filepath.Join(dst, relPath)
-
-		if info.IsDir() {
-			// Create directory
-			return os.MkdirAll(dstPath, info.Mode().Perm())
-		} else {
-			// Copy file
-			data, err := os.ReadFile(path)
-			if err != nil {
-				return err
-			}
-			return os.WriteFile(dstPath, data, info.Mode().Perm())
+		if !info.IsDir() {
+			files = append(files, installFile{Src: path, Dst: filepath.Join(dst, relPath)})
 		}
+		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
 }
 
+// trackedPathsForDirectory returns the files under src that are tracked by
+// Git, and the directories containing them, relative to src. Outside a Git
+// checkout, e.g. in a source export, every file counts as tracked.
 func trackedPathsForDirectory(src string) (map[string]struct{}, map[string]struct{}, error) {
 	gitRootOutput, err := exec.Command("git", "-C", src, "rev-parse", "--show-toplevel").Output()
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to resolve git root for %s: %w", src, err)
+		return allPathsForDirectory(src)
 	}
 
 	gitRoot := strings.TrimSpace(string(gitRootOutput))
@@ -276,3 +354,28 @@ func trackedPathsForDirectory(src string) (map[string]struct{}, map[string]struc
 
 	return trackedFiles, trackedDirs, nil
 }
+
+func allPathsForDirectory(src string) (map[string]struct{}, map[string]struct{}, error) {
+	files := map[string]struct{}{}
+	dirs := map[string]struct{}{}
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			dirs[relPath] = struct{}{}
+		} else {
+			files[relPath] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list files in %s: %w", src, err)
+	}
+
+	return files, dirs, nil
+}
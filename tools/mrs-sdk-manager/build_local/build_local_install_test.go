@@ -53,7 +53,7 @@ func TestInstallBuildsUsesConfiguredSDKRoot(t *testing.T) {
 	initTestRepo(t, repoRoot)
 	createFakeSDKRepo(t, repoRoot)
 
-	if err := InstallBuilds(repoRoot); err != nil {
+	if err := InstallBuilds(repoRoot, testTargetMatrix(t).AllBuildTargets()); err != nil {
 		t.Fatalf("InstallBuilds returned error: %v", err)
 	}
 
@@ -62,8 +62,8 @@ func TestInstallBuildsUsesConfiguredSDKRoot(t *testing.T) {
 	assertFileExists(t, filepath.Join(versionRoot, "lib", "cmake", "mrs-sdk-qt", "config.cmake"))
 	assertFileExists(t, filepath.Join(versionRoot, "lib", "qmake", "mrs-sdk-qt", "config.pri"))
 
-	for _, target := range AllBuildTargets() {
-		assertFileExists(t, filepath.Join(versionRoot, target.InstTreeDir(), "libmrs-sdk-qt.a"))
+	for _, target := range testTargetMatrix(t).AllBuildTargets() {
+		assertFileExists(t, filepath.Join(versionRoot, target.InstLibDir(), "libmrs-sdk-qt.a"))
 	}
 
 	legacyRoot := filepath.Join(homeDir, "mrs-sdk-qt")
@@ -72,6 +72,38 @@ func TestInstallBuildsUsesConfiguredSDKRoot(t *testing.T) {
 	}
 }
 
+// TestInstallBuildsKeepsUnselectedTargets verifies that installing a subset
+// of targets into an existing version leaves the libraries of the other
+// targets in place.
+func TestInstallBuildsKeepsUnselectedTargets(t *testing.T) {
+	repoRoot := t.TempDir()
+	sdkRoot := filepath.Join(t.TempDir(), "custom-sdk-root")
+
+	t.Setenv("MRS_SDK_QT_ROOT", sdkRoot)
+
+	initTestRepo(t, repoRoot)
+	createFakeSDKRepo(t, repoRoot)
+
+	versionRoot := filepath.Join(sdkRoot, "0.0.0")
+	existingLib := filepath.Join(versionRoot, "lib", "qt5", "yocto", "linux_arm_mconn", "debug", "libmrs-sdk-qt.a")
+	writeTestFile(t, existingLib, "previous install")
+
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{OSes: []string{"desktop"}})
+	if err != nil {
+		t.Fatalf("expected desktop selection to succeed, got error: %v", err)
+	}
+
+	if err := InstallBuilds(repoRoot, targets); err != nil {
+		t.Fatalf("InstallBuilds returned error: %v", err)
+	}
+
+	for _, target := range targets {
+		assertFileExists(t, filepath.Join(versionRoot, target.InstLibDir(), "libmrs-sdk-qt.a"))
+	}
+	assertFileExists(t, existingLib)
+	assertFileMissing(t, filepath.Join(versionRoot, "lib", "qt5", "buildroot", "linux_arm_fusion", "debug", "libmrs-sdk-qt.a"))
+}
+
 // initTestRepo creates a minimal Git repository fixture with a deterministic
 // identity so tests can exercise Git-aware install logic without depending on
 // any global user configuration.
@@ -88,7 +120,7 @@ func initTestRepo(t *testing.T, repoRoot string) {
 func TestInstallBuildsRequiresSDKRoot(t *testing.T) {
 	t.Setenv("MRS_SDK_QT_ROOT", "")
 
-	err := InstallBuilds(t.TempDir())
+	err := InstallBuilds(t.TempDir(), testTargetMatrix(t).AllBuildTargets())
 	if err == nil {
 		t.Fatal("expected InstallBuilds to fail when MRS_SDK_QT_ROOT is unset")
 	}
@@ -123,8 +155,8 @@ func createFakeSDKRepo(t *testing.T, repoRoot string) {
 	writeTestFile(t, filepath.Join(repoRoot, "lib", "qmake", "mrs-sdk-qt", "config.pri"), "qmake")
 	runGit(t, repoRoot, "add", "lib")
 
-	for _, target := range AllBuildTargets() {
-		writeTestFile(t, filepath.Join(repoRoot, "build", target.BuildDir(), "artifacts", "libmrs-sdk-qt.a"), target.BuildDir())
+	for _, target := range testTargetMatrix(t).AllBuildTargets() {
+		writeTestFile(t, filepath.Join(repoRoot, "build", target.BuildDir(), "artifacts", "libmrs-sdk-qt.a"), string(testArtifact(target)))
 	}
 }
 
@@ -180,3 +212,24 @@ func assertFileMissing(t *testing.T, path string) {
 		t.Fatalf("expected file %s to be absent, stat error: %v", path, err)
 	}
 }
+
+// TestTrackedPathsForDirectoryOutsideGit verifies that every file counts as
+// tracked in a source export without Git metadata.
+func TestTrackedPathsForDirectoryOutsideGit(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "include", "sdk.h"), "")
+	writeTestFile(t, filepath.Join(src, "CMakeLists.txt"), "")
+
+	files, dirs, err := trackedPathsForDirectory(src)
+	if err != nil {
+		t.Fatalf("trackedPathsForDirectory returned error: %v", err)
+	}
+	for _, path := range []string{filepath.Join("include", "sdk.h"), "CMakeLists.txt"} {
+		if _, ok := files[path]; !ok {
+			t.Fatalf("expected %s to be tracked, got %v", path, files)
+		}
+	}
+	if _, ok := dirs["include"]; !ok {
+		t.Fatalf("expected include to be a tracked directory, got %v", dirs)
+	}
+}
@@ -1,8 +1,18 @@
 package buildlocal
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/fatih/color"
 )
 
 // TestGetBuildConfigsPassesYoctoSetupScript verifies that repo-local Yocto
@@ -12,16 +22,7 @@ import (
 func TestGetBuildConfigsPassesYoctoSetupScript(t *testing.T) {
 	t.Setenv("MRS_SDK_QT_ROOT", "/tmp/mrs-sdk-root")
 
-	configs := getBuildConfigs("/tmp/mrs-sdk-qt", map[string]string{
-		"YOCTO_QT5_SYSROOT":          "/tmp/yocto/sysroot",
-		"YOCTO_QT5_CXX_COMPILER":     "/tmp/yocto/bin/arm-g++",
-		"YOCTO_QT5_ENV_SETUP_SCRIPT": "/tmp/yocto/environment-setup",
-		"BUILDROOT_QT5_SYSROOT":      "/tmp/buildroot/sysroot",
-		"BUILDROOT_QT5_CXX_COMPILER": "/tmp/buildroot/bin/arm-g++",
-		"DESKTOP_CXX_COMPILER":       "/usr/bin/g++",
-		"DESKTOP_QT5_PREFIX":         "/opt/Qt/5",
-		"DESKTOP_QT6_PREFIX":         "/opt/Qt/6",
-	})
+	configs := getBuildConfigs("/tmp/mrs-sdk-qt", testEnvConfig(), testBuildTools(), testTargetMatrix(t).AllBuildTargets())
 
 	var yoctoConfig *BuildConfig
 	for i := range configs {
@@ -35,11 +36,246 @@ func TestGetBuildConfigsPassesYoctoSetupScript(t *testing.T) {
 	}
 
 	expectedArg := "-DYOCTO_QT5_ENV_SETUP_SCRIPT:FILEPATH=/tmp/yocto/environment-setup"
-	for _, arg := range yoctoConfig.CmakeCmd {
+	for _, arg := range yoctoConfig.ConfigureCmd {
 		if arg == expectedArg {
 			return
 		}
 	}
 
-	t.Fatalf("expected Yocto configure args to include %q, got %s", expectedArg, strings.Join(yoctoConfig.CmakeCmd, " "))
+	t.Fatalf("expected Yocto configure args to include %q, got %s", expectedArg, strings.Join(yoctoConfig.ConfigureCmd, " "))
+}
+
+// TestRequiredEnvVarsForScopeOnlyCoversSelectedTargets verifies that a
+// desktop-only build does not demand cross-compilation toolchain settings.
+func TestRequiredEnvVarsForScopeOnlyCoversSelectedTargets(t *testing.T) {
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug", "release"}, TargetFilter{OSes: []string{"desktop"}, QtVersions: []string{"qt6"}})
+	if err != nil {
+		t.Fatalf("expected desktop qt6 selection to succeed, got error: %v", err)
+	}
+
+	var keys []string
+	for _, v := range requiredEnvVarsForScope(BuildScopeLibs, targets) {
+		keys = append(keys, v.Key)
+	}
+
+	expected := []string{"DESKTOP_CXX_COMPILER", "DESKTOP_QT6_PREFIX"}
+	if strings.Join(keys, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected required keys %v, got %v", expected, keys)
+	}
+}
+
+// TestGetBuildConfigsUsesQt6YoctoEnvironment verifies that the NeuralPlex
+// target is configured from the YOCTO_QT6_* keys and the Qt6 Yocto toolchain
+// helper rather than reusing the MConn Qt5 SDK.
+func TestGetBuildConfigsUsesQt6YoctoEnvironment(t *testing.T) {
+	t.Setenv("MRS_SDK_QT_ROOT", "/tmp/mrs-sdk-root")
+
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{Devices: []string{"neuralplex"}})
+	if err != nil {
+		t.Fatalf("expected neuralplex selection to succeed, got error: %v", err)
+	}
+
+	configs := getBuildConfigs("/tmp/mrs-sdk-qt", testEnvConfig(), testBuildTools(), targets)
+	if len(configs) != 1 {
+		t.Fatalf("expected a single NeuralPlex build configuration, got %d", len(configs))
+	}
+
+	expectedArgs := []string{
+		"-DCMAKE_SYSROOT:PATH=/tmp/yocto-qt6/sysroot",
+		"-DCMAKE_CXX_COMPILER:STRING=/tmp/yocto-qt6/bin/aarch64-g++",
+		"-DCMAKE_CXX_COMPILER_TARGET:STRING=aarch64-poky-linux",
+		"-DCMAKE_TOOLCHAIN_FILE:STRING=/tmp/mrs-sdk-qt/lib/cmake/mrs-sdk-qt/toolchains/yocto-qt6.cmake",
+		"-DYOCTO_QT6_ENV_SETUP_SCRIPT:FILEPATH=/tmp/yocto-qt6/environment-setup",
+	}
+	for _, expectedArg := range expectedArgs {
+		if !slices.Contains(configs[0].ConfigureCmd, expectedArg) {
+			t.Fatalf("expected NeuralPlex configure args to include %q, got %s", expectedArg, strings.Join(configs[0].ConfigureCmd, " "))
+		}
+	}
+}
+
+// testEnvConfig returns a fully populated environment config so build
+// configuration tests can exercise every target in the matrix.
+func testEnvConfig() map[string]string {
+	return map[string]string{
+		"YOCTO_QT5_SYSROOT":          "/tmp/yocto/sysroot",
+		"YOCTO_QT5_CXX_COMPILER":     "/tmp/yocto/bin/arm-g++",
+		"YOCTO_QT5_ENV_SETUP_SCRIPT": "/tmp/yocto/environment-setup",
+		"YOCTO_QT6_SYSROOT":          "/tmp/yocto-qt6/sysroot",
+		"YOCTO_QT6_CXX_COMPILER":     "/tmp/yocto-qt6/bin/aarch64-g++",
+		"YOCTO_QT6_ENV_SETUP_SCRIPT": "/tmp/yocto-qt6/environment-setup",
+		"BUILDROOT_QT5_SYSROOT":      "/tmp/buildroot/sysroot",
+		"BUILDROOT_QT5_CXX_COMPILER": "/tmp/buildroot/bin/arm-g++",
+		"DESKTOP_CXX_COMPILER":       "/usr/bin/g++",
+		"DESKTOP_QT5_PREFIX":         "/opt/Qt/5",
+		"DESKTOP_QT6_PREFIX":         "/opt/Qt/6",
+	}
+}
+
+// TestRunAllBuildsKeepGoingReportsEveryFailure verifies that --keep-going
+// lets every target run and reports all failures, while the default mode
+// stops scheduling new targets after the first failure.
+func TestRunAllBuildsKeepGoingReportsEveryFailure(t *testing.T) {
+	repoRoot := t.TempDir()
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{OSes: []string{"desktop"}})
+	if err != nil {
+		t.Fatalf("expected desktop selection to succeed, got error: %v", err)
+	}
+
+	var configs []BuildConfig
+	for _, target := range targets {
+		configs = append(configs, BuildConfig{
+			Target:       target,
+			BuildDir:     filepath.Join(repoRoot, "build", target.BuildDir()),
+			ConfigureCmd: []string{"false"},
+			BuildCmd:     []string{"true"},
+		})
+	}
+
+	err = runAllBuilds(context.Background(), repoRoot, configs, 1, 1, true, newProgressReporter(OutputPlain, io.Discard))
+	if err == nil {
+		t.Fatal("expected failing builds to return an error")
+	}
+	for _, config := range configs {
+		if !strings.Contains(err.Error(), config.Target.BuildDir()) {
+			t.Fatalf("expected error to name %s, got %v", config.Target.BuildDir(), err)
+		}
+		assertFileExists(t, config.logPath())
+	}
+
+	for _, config := range configs {
+		if err := os.Remove(config.logPath()); err != nil {
+			t.Fatalf("failed to remove build log: %v", err)
+		}
+	}
+
+	err = runAllBuilds(context.Background(), repoRoot, configs, 1, 1, false, newProgressReporter(OutputPlain, io.Discard))
+	if err == nil {
+		t.Fatal("expected failing builds to return an error")
+	}
+	ran := 0
+	for _, config := range configs {
+		if _, statErr := os.Stat(config.logPath()); statErr == nil {
+			ran++
+		}
+	}
+	if ran != 1 || strings.Contains(err.Error(), ",") {
+		t.Fatalf("expected only one target to run without --keep-going, %d ran: %v", ran, err)
+	}
+}
+
+// TestRunAllBuildsCancelsRunningBuilds verifies that cancelling the context
+// kills the whole process tree of a running build instead of waiting for it,
+// and that running and pending targets are reported as cancelled.
+func TestRunAllBuildsCancelsRunningBuilds(t *testing.T) {
+	repoRoot := t.TempDir()
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{OSes: []string{"desktop"}})
+	if err != nil {
+		t.Fatalf("expected desktop selection to succeed, got error: %v", err)
+	}
+
+	var configs []BuildConfig
+	for _, target := range targets {
+		// bash waits for sleep, which also holds the log pipe open, so the
+		// build only returns early if the whole process group is killed.
+		configs = append(configs, BuildConfig{
+			Target:       target,
+			BuildDir:     filepath.Join(repoRoot, "build", target.BuildDir()),
+			ConfigureCmd: []string{"/bin/bash", "-c", "sleep 30; true"},
+			BuildCmd:     []string{"true"},
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(500*time.Millisecond, cancel)
+
+	var out bytes.Buffer
+	start := time.Now()
+	err = runAllBuilds(ctx, repoRoot, configs, 1, 1, false, newProgressReporter(OutputPlain, &out))
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("expected cancellation to stop the build promptly, took %s", elapsed)
+	}
+	if err == nil || !strings.Contains(err.Error(), "interrupted") {
+		t.Fatalf("expected an interrupted error, got %v", err)
+	}
+	for _, target := range targets {
+		if !strings.Contains(out.String(), target.BuildDir()+": cancelled") {
+			t.Fatalf("expected %s to be reported as cancelled, got:\n%s", target.BuildDir(), out.String())
+		}
+	}
+
+	// Only one target was running; its log must record the cancellation.
+	var logs []string
+	for _, config := range configs {
+		if data, err := os.ReadFile(config.logPath()); err == nil {
+			logs = append(logs, string(data))
+		}
+	}
+	if len(logs) != 1 || !strings.Contains(logs[0], "Build cancelled") {
+		t.Fatalf("expected one build log recording the cancellation, got %q", logs)
+	}
+}
+
+// TestRunBuildStagesReportsStagesTogether verifies that the builds of every
+// stage are numbered as one run, that a stage's prepare output is printed
+// after the status lines, and that a failed stage cancels the later ones
+// without preparing them.
+func TestRunBuildStagesReportsStagesTogether(t *testing.T) {
+	repoRoot := t.TempDir()
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{Targets: []string{"desktop-desktop-qt6"}})
+	if err != nil {
+		t.Fatalf("expected selection to succeed, got error: %v", err)
+	}
+
+	var out bytes.Buffer
+	output := color.Output
+	color.Output = &out
+	t.Cleanup(func() { color.Output = output })
+
+	stages := func(libraryCmd string, prepared *bool) []buildStage {
+		library := BuildConfig{
+			Target:       targets[0],
+			BuildDir:     filepath.Join(repoRoot, "build", targets[0].BuildDir()),
+			ConfigureCmd: []string{libraryCmd},
+			BuildCmd:     []string{"true"},
+		}
+		demo := BuildConfig{
+			Target:       targets[0],
+			Project:      "demo",
+			BuildDir:     filepath.Join(repoRoot, "build", "demos", "demo", targets[0].BuildDir()),
+			ConfigureCmd: []string{"true"},
+			BuildCmd:     []string{"true"},
+		}
+		prepare := func() error {
+			*prepared = true
+			fmt.Fprintln(color.Output, "installed the libraries")
+			return nil
+		}
+		return []buildStage{
+			{configs: []BuildConfig{library}, concurrency: 1, jobsPerTarget: 1},
+			{configs: []BuildConfig{demo}, concurrency: 1, jobsPerTarget: 1, prepare: prepare},
+		}
+	}
+
+	prepared := false
+	if err := runBuildStages(context.Background(), repoRoot, stages("true", &prepared), true, newProgressReporter(OutputPlain, &out)); err != nil {
+		t.Fatalf("runBuildStages returned error: %v", err)
+	}
+	expected := "[1/2] desktop-desktop-qt6-debug: success"
+	if !prepared || !strings.Contains(out.String(), expected) || !strings.Contains(out.String(), "[2/2] demo/desktop-desktop-qt6-debug: success") {
+		t.Fatalf("expected both stages in one run, got:\n%s", out.String())
+	}
+	if strings.Index(out.String(), "installed the libraries") < strings.LastIndex(out.String(), "[2/2]") {
+		t.Fatalf("expected the prepare output after the status lines, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	prepared = false
+	err = runBuildStages(context.Background(), repoRoot, stages("false", &prepared), true, newProgressReporter(OutputPlain, &out))
+	if err == nil || !strings.Contains(err.Error(), "desktop-desktop-qt6-debug") {
+		t.Fatalf("expected the library failure to be reported, got %v", err)
+	}
+	if prepared || !strings.Contains(out.String(), "[2/2] demo/desktop-desktop-qt6-debug: cancelled") {
+		t.Fatalf("expected the demo stage to be cancelled without being prepared, got:\n%s", out.String())
+	}
 }
@@ -0,0 +1,255 @@
+package buildlocal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mrs-sdk-manager/utils"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// buildLogFileName is the file inside build/<target>/ that receives the
+// timestamped output of the most recent build of that target.
+const buildLogFileName = "build.log"
+
+// logTimestampLayout prefixes every line written to a build log.
+const logTimestampLayout = "2006-01-02 15:04:05"
+
+// errorLinePattern matches the compiler, linker, CMake and Ninja lines that
+// explain why a build failed.
+var errorLinePattern = regexp.MustCompile(`(?i)(\berror\b|fatal error|undefined reference|CMake Error|^FAILED:|ninja: build stopped|make(\[\d+\])?: \*\*\*)`)
+
+// timestampWriter prefixes every line written through it with the current
+// time. It is safe for concurrent use so stdout and stderr can share it.
+type timestampWriter struct {
+	mu        sync.Mutex
+	w         io.Writer
+	now       func() time.Time
+	lineStart bool
+}
+
+func newTimestampWriter(w io.Writer) *timestampWriter {
+	return &timestampWriter{w: w, now: time.Now, lineStart: true}
+}
+
+func (tw *timestampWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	written := len(p)
+	for len(p) > 0 {
+		if tw.lineStart {
+			if _, err := fmt.Fprintf(tw.w, "[%s] ", tw.now().Format(logTimestampLayout)); err != nil {
+				return 0, err
+			}
+			tw.lineStart = false
+		}
+
+		chunk := p
+		if i := slices.Index(p, '\n'); i >= 0 {
+			chunk = p[:i+1]
+			tw.lineStart = true
+		}
+		if _, err := tw.w.Write(chunk); err != nil {
+			return 0, err
+		}
+		p = p[len(chunk):]
+	}
+
+	return written, nil
+}
+
+// stripTimestamp removes the prefix added by timestampWriter.
+func stripTimestamp(line string) string {
+	if strings.HasPrefix(line, "[") {
+		if i := strings.Index(line, "] "); i == len(logTimestampLayout)+1 {
+			return line[i+2:]
+		}
+	}
+	return line
+}
+
+// isErrorLine reports whether a log line belongs in the --errors view.
+func isErrorLine(line string) bool {
+	return errorLinePattern.MatchString(stripTimestamp(line))
+}
+
+// logExcerpt returns the error lines of a build log without timestamps. When
+// no line looks like an error, the tail of the log is returned instead. At most
+// maxLines lines are returned.
+func logExcerpt(logPath string, maxLines int) string {
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		return fmt.Sprintf("(failed to read %s: %v)", logPath, err)
+	}
+
+	var all, errorLines []string
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		line = stripTimestamp(line)
+		all = append(all, line)
+		if errorLinePattern.MatchString(line) {
+			errorLines = append(errorLines, line)
+		}
+	}
+
+	excerpt := errorLines
+	if len(excerpt) == 0 {
+		excerpt = all
+	}
+	if len(excerpt) > maxLines {
+		excerpt = excerpt[len(excerpt)-maxLines:]
+	}
+
+	return strings.Join(excerpt, "\n")
+}
+
+// ShowLogs prints the build log of a target. targetArg may be a build
+// directory name (e.g. "mconn-yocto-qt5-debug") or a target name without the
+// build type when only one build type has a log. An empty targetArg selects
+// the most recently written log. With demo set, the log of that demo's build
+// is shown instead of the library's, and with tests set, the log of the unit
+// test build. With follow set, new output is printed as it is appended until
+// the process is interrupted. With errorsOnly set, only compiler/CMake error
+// lines are printed.
+func ShowLogs(sdkRoot, targetArg, demo string, tests, follow, errorsOnly bool) error {
+	matrix, err := LoadTargetMatrix(sdkRoot)
+	if err != nil {
+		return err
+	}
+
+	logPath, err := resolveBuildLog(sdkRoot, matrix, demo, tests, targetArg)
+	if err != nil {
+		return err
+	}
+
+	utils.PrintTaskStart(fmt.Sprintf("Build log %s", logPath))
+	return printLog(os.Stdout, logPath, follow, errorsOnly)
+}
+
+// resolveBuildLog finds the log file selected by targetArg among the library
+// builds, the builds of demo, or with tests set the unit test builds.
+func resolveBuildLog(sdkRoot string, matrix *TargetMatrix, demo string, tests bool, targetArg string) (string, error) {
+	buildRoot := filepath.Join(sdkRoot, "build")
+	switch {
+	case demo != "" && tests:
+		return "", fmt.Errorf("a demo and the unit tests cannot be selected together")
+	case demo != "":
+		if demo == "." || demo == ".." || strings.ContainsAny(demo, `/\`) {
+			return "", fmt.Errorf("invalid demo name %q", demo)
+		}
+		buildRoot = filepath.Join(buildRoot, "demos", demo)
+	case tests:
+		buildRoot = filepath.Join(buildRoot, "tests")
+	}
+
+	target, err := resolveTargetFile(buildRoot, matrix, buildLogFileName, "build log", targetArg)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(buildRoot, target.BuildDir(), buildLogFileName), nil
+}
+
+// resolveTargetFile finds the target selected by targetArg among those whose
+// build directory in buildRoot, e.g. <repo>/build, contains fileName.
+// targetArg is a build directory name, or a target name when only one of its
+// build types has the file. An empty targetArg selects the most recently
+// written file. what describes the file in errors.
+func resolveTargetFile(buildRoot string, matrix *TargetMatrix, fileName, what, targetArg string) (BuildTarget, error) {
+	var available []BuildTarget
+	var availableNames []string
+	var newest BuildTarget
+	var newestTime time.Time
+	for _, target := range matrix.AllBuildTargets() {
+		info, err := os.Stat(filepath.Join(buildRoot, target.BuildDir(), fileName))
+		if err != nil {
+			continue
+		}
+		available = append(available, target)
+		availableNames = append(availableNames, target.BuildDir())
+		if info.ModTime().After(newestTime) {
+			newest, newestTime = target, info.ModTime()
+		}
+	}
+
+	if len(available) == 0 {
+		return BuildTarget{}, fmt.Errorf("no %ss found in %s; run 'mrs-sdk-manager build-local' first", what, buildRoot)
+	}
+
+	if targetArg == "" {
+		return newest, nil
+	}
+
+	var matches []BuildTarget
+	var matchNames []string
+	for _, target := range available {
+		if target.BuildDir() == targetArg {
+			return target, nil
+		}
+		if target.Name() == targetArg {
+			matches = append(matches, target)
+			matchNames = append(matchNames, target.BuildDir())
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return BuildTarget{}, fmt.Errorf("no %s for %q (available: %s)", what, targetArg, strings.Join(availableNames, ", "))
+	case 1:
+		return matches[0], nil
+	default:
+		return BuildTarget{}, fmt.Errorf("target %q is ambiguous (matches: %s)", targetArg, strings.Join(matchNames, ", "))
+	}
+}
+
+// printLog copies the log to out, optionally filtering to error lines and
+// following the file as it grows.
+func printLog(out io.Writer, logPath string, follow, errorsOnly bool) error {
+	f, err := os.Open(logPath)
+	if err != nil {
+		return fmt.Errorf("failed to open build log: %w", err)
+	}
+	defer f.Close()
+
+	var offset int64
+	var partial string
+	for {
+		reader := bufio.NewReader(f)
+		for {
+			line, err := reader.ReadString('\n')
+			offset += int64(len(line))
+			if err != nil {
+				// Keep incomplete lines until the rest of them is written.
+				partial += line
+				break
+			}
+			line = partial + line
+			partial = ""
+			if !errorsOnly || isErrorLine(line) {
+				fmt.Fprint(out, line)
+			}
+		}
+
+		if !follow {
+			if partial != "" && (!errorsOnly || isErrorLine(partial)) {
+				fmt.Fprintln(out, partial)
+			}
+			return nil
+		}
+
+		time.Sleep(500 * time.Millisecond)
+
+		// A new build truncates the log, so start over from the beginning.
+		if info, err := f.Stat(); err == nil && info.Size() < offset {
+			offset, partial = 0, ""
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to read build log: %w", err)
+		}
+	}
+}
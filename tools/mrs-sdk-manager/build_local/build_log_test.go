@@ -0,0 +1,110 @@
+package buildlocal
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTimestampWriterPrefixesEveryLine verifies that lines split across
+// several writes get exactly one timestamp each.
+func TestTimestampWriterPrefixesEveryLine(t *testing.T) {
+	var buf bytes.Buffer
+	writer := newTimestampWriter(&buf)
+	writer.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	for _, chunk := range []string{"first ", "line\nsecond line\nthi", "rd line\n"} {
+		if _, err := writer.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+
+	expected := "[2026-01-02 03:04:05] first line\n" +
+		"[2026-01-02 03:04:05] second line\n" +
+		"[2026-01-02 03:04:05] third line\n"
+	if buf.String() != expected {
+		t.Fatalf("expected timestamped output:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+// TestLogExcerptPrefersErrorLines verifies that the inline failure summary
+// shows the compiler errors rather than the whole build output.
+func TestLogExcerptPrefersErrorLines(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), buildLogFileName)
+	writeTestFile(t, logPath, strings.Join([]string{
+		"[2026-01-02 03:04:05] -- Configuring done",
+		"[2026-01-02 03:04:05] [1/3] Building CXX object BuildInfo.cpp.o",
+		"[2026-01-02 03:04:06] src/BuildInfo.cpp:10:5: error: 'foo' was not declared in this scope",
+		"[2026-01-02 03:04:06] ninja: build stopped: subcommand failed.",
+	}, "\n")+"\n")
+
+	excerpt := logExcerpt(logPath, 20)
+	expected := "src/BuildInfo.cpp:10:5: error: 'foo' was not declared in this scope\nninja: build stopped: subcommand failed."
+	if excerpt != expected {
+		t.Fatalf("expected excerpt:\n%s\ngot:\n%s", expected, excerpt)
+	}
+}
+
+// TestResolveBuildLogMatchesTargetNames verifies that logs can be selected by
+// full build directory name or, when unambiguous, without the build type.
+func TestResolveBuildLogMatchesTargetNames(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeTestFile(t, filepath.Join(repoRoot, "build", "mconn-yocto-qt5-debug", buildLogFileName), "debug")
+	writeTestFile(t, filepath.Join(repoRoot, "build", "mconn-yocto-qt5-release", buildLogFileName), "release")
+	writeTestFile(t, filepath.Join(repoRoot, "build", "fusion-buildroot-qt5-debug", buildLogFileName), "fusion")
+
+	logPath, err := resolveBuildLog(repoRoot, testTargetMatrix(t), "", false, "fusion-buildroot-qt5")
+	if err != nil {
+		t.Fatalf("expected unambiguous target to resolve, got error: %v", err)
+	}
+	if logPath != filepath.Join(repoRoot, "build", "fusion-buildroot-qt5-debug", buildLogFileName) {
+		t.Fatalf("unexpected log path %s", logPath)
+	}
+
+	if _, err := resolveBuildLog(repoRoot, testTargetMatrix(t), "", false, "mconn-yocto-qt5-release"); err != nil {
+		t.Fatalf("expected full build directory name to resolve, got error: %v", err)
+	}
+	if _, err := resolveBuildLog(repoRoot, testTargetMatrix(t), "", false, "mconn-yocto-qt5"); err == nil {
+		t.Fatal("expected a target with several build types to be ambiguous")
+	}
+	if _, err := resolveBuildLog(repoRoot, testTargetMatrix(t), "", false, "desktop-desktop-qt6"); err == nil {
+		t.Fatal("expected a target without a log to return an error")
+	}
+}
+
+// TestResolveBuildLogFindsDemoAndTestLogs verifies that the logs of demo and
+// unit test builds can be selected, separately from the library logs.
+func TestResolveBuildLogFindsDemoAndTestLogs(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeTestFile(t, filepath.Join(repoRoot, "build", "mconn-yocto-qt5-debug", buildLogFileName), "library")
+	writeTestFile(t, filepath.Join(repoRoot, "build", "demos", "can-simulator", "mconn-yocto-qt5-debug", buildLogFileName), "demo")
+	writeTestFile(t, filepath.Join(repoRoot, "build", "tests", "desktop-desktop-qt6-debug", buildLogFileName), "tests")
+
+	logPath, err := resolveBuildLog(repoRoot, testTargetMatrix(t), "can-simulator", false, "mconn-yocto-qt5")
+	if err != nil {
+		t.Fatalf("expected the demo log to resolve, got error: %v", err)
+	}
+	if logPath != filepath.Join(repoRoot, "build", "demos", "can-simulator", "mconn-yocto-qt5-debug", buildLogFileName) {
+		t.Fatalf("unexpected demo log path %s", logPath)
+	}
+
+	logPath, err = resolveBuildLog(repoRoot, testTargetMatrix(t), "", true, "")
+	if err != nil {
+		t.Fatalf("expected the test log to resolve, got error: %v", err)
+	}
+	if logPath != filepath.Join(repoRoot, "build", "tests", "desktop-desktop-qt6-debug", buildLogFileName) {
+		t.Fatalf("unexpected test log path %s", logPath)
+	}
+
+	if _, err := resolveBuildLog(repoRoot, testTargetMatrix(t), "", false, "desktop-desktop-qt6"); err == nil {
+		t.Fatal("expected a test log not to be found among the library logs")
+	}
+	if _, err := resolveBuildLog(repoRoot, testTargetMatrix(t), "other-demo", false, ""); err == nil {
+		t.Fatal("expected a demo without logs to return an error")
+	}
+	if _, err := resolveBuildLog(repoRoot, testTargetMatrix(t), "../tests", false, ""); err == nil {
+		t.Fatal("expected an invalid demo name to be rejected")
+	}
+}
@@ -0,0 +1,132 @@
+package buildlocal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// buildStep is a single command of a build plan. Its argv is executed
+// directly, never through a shell, so paths may contain spaces or shell
+// metacharacters.
+type buildStep struct {
+	name string
+	args []string
+}
+
+// plan returns the steps that build a configuration, limiting the build step
+// to the given number of parallel jobs.
+func (c BuildConfig) plan(jobs int) []buildStep {
+	jobsFlag := c.JobsFlag
+	if jobsFlag == "" {
+		jobsFlag = "--parallel"
+	}
+	steps := []buildStep{
+		{name: "configure", args: c.ConfigureCmd},
+		{name: "build", args: append(slices.Clone(c.BuildCmd), jobsFlag, strconv.Itoa(jobs))},
+	}
+	if len(c.TestCmd) > 0 {
+		steps = append(steps, buildStep{name: "test", args: c.TestCmd})
+	}
+	return steps
+}
+
+// subject names what the configuration builds, e.g. "SDK lib" or
+// "can-simulator".
+func (c BuildConfig) subject() string {
+	if c.Project == "" {
+		return "SDK lib"
+	}
+	return c.Project
+}
+
+// label identifies the configuration in progress output and error summaries,
+// e.g. "mconn-yocto-qt5-debug" or "can-simulator/mconn-yocto-qt5-debug".
+func (c BuildConfig) label() string {
+	if c.Project == "" {
+		return c.Target.BuildDir()
+	}
+	return c.Project + "/" + c.Target.BuildDir()
+}
+
+// logPath returns the log file of the configuration's most recent build.
+func (c BuildConfig) logPath() string {
+	return filepath.Join(c.BuildDir, buildLogFileName)
+}
+
+// captureEnvironment sources a toolchain setup script (e.g. a Yocto
+// environment-setup-* script) in a throwaway shell and returns the resulting
+// environment in os/exec form. The script path is passed as an argument
+// rather than spliced into the shell code. Anything the script prints goes to
+// log.
+func captureEnvironment(ctx context.Context, setupScript string, log io.Writer) ([]string, error) {
+	var envOut bytes.Buffer
+	cmd := exec.CommandContext(ctx, "/bin/bash", "-c", `script="$1"; shift; . "$script" >&2 && env -0`, "bash", setupScript)
+	group := configureProcessGroup(cmd)
+	cmd.Stdout = &envOut
+	cmd.Stderr = log
+
+	err := cmd.Run()
+	group.finished()
+	if err != nil {
+		return nil, fmt.Errorf("failed to source %s: %w", setupScript, err)
+	}
+
+	var environ []string
+	for _, entry := range strings.Split(envOut.String(), "\x00") {
+		if strings.Contains(entry, "=") {
+			environ = append(environ, entry)
+		}
+	}
+	return environ, nil
+}
+
+// lookPathIn resolves a program name against the PATH of environ, which may
+// differ from our own PATH after a setup script has been sourced. Paths and
+// programs that cannot be found are returned unchanged so that the step
+// fails with a normal "not found" error. A nil environ means our own
+// environment.
+func lookPathIn(program string, environ []string) string {
+	if environ == nil || strings.Contains(program, "/") {
+		return program
+	}
+
+	var pathValue string
+	for _, entry := range environ {
+		if value, ok := strings.CutPrefix(entry, "PATH="); ok {
+			pathValue = value
+		}
+	}
+	for _, dir := range filepath.SplitList(pathValue) {
+		candidate := filepath.Join(dir, program)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return candidate
+		}
+	}
+	return program
+}
+
+// shellSafeArg matches arguments that can be logged without quoting.
+var shellSafeArg = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// formatCommand renders argv for the build log, quoting arguments so that the
+// logged line can be pasted into a shell.
+func formatCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if shellSafeArg.MatchString(arg) {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,149 @@
+package buildlocal
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestRunBuildPassesArgumentsVerbatim verifies that build steps are executed
+// without a shell, so paths with spaces and shell metacharacters reach the
+// tools unchanged, and that the build step receives the job limit.
+func TestRunBuildPassesArgumentsVerbatim(t *testing.T) {
+	sdkRoot := filepath.Join(t.TempDir(), "sdk root $(touch pwned)")
+	target := testTargetMatrix(t).AllBuildTargets()[0]
+	buildDir := filepath.Join(sdkRoot, "build", target.BuildDir())
+	configured := filepath.Join(buildDir, "configured; rm -rf *")
+
+	config := BuildConfig{
+		Target:       target,
+		BuildDir:     buildDir,
+		ConfigureCmd: []string{"touch", configured},
+		BuildCmd:     []string{"/bin/bash", "-c", `echo "$@" > built`, "bash", "--target", "all"},
+		Fingerprint:  "fingerprint",
+	}
+	if err := runBuild(context.Background(), sdkRoot, config, 3); err != nil {
+		t.Fatalf("expected build to succeed, got error: %v", err)
+	}
+
+	assertFileExists(t, configured)
+	assertFileMissing(t, filepath.Join(sdkRoot, "pwned"))
+	built, err := os.ReadFile(filepath.Join(sdkRoot, "built"))
+	if err != nil {
+		t.Fatalf("expected build step to run in the SDK root: %v", err)
+	}
+	if strings.TrimSpace(string(built)) != "--target all --parallel 3" {
+		t.Fatalf("expected build step to receive the job limit, got %q", built)
+	}
+	assertFileExists(t, filepath.Join(buildDir, stampFileName))
+}
+
+// TestRunBuildUsesCapturedSetupEnvironment verifies that a setup script with
+// spaces in its path is sourced once and its environment is visible to every
+// build step.
+func TestRunBuildUsesCapturedSetupEnvironment(t *testing.T) {
+	sdkRoot := t.TempDir()
+	script := filepath.Join(sdkRoot, "yocto sdk", "environment-setup cortexa9")
+	writeTestFile(t, script, "echo sourcing toolchain\nexport OE_CMAKE_TOOLCHAIN_FILE='/opt/yocto sdk/toolchain.cmake'\n")
+
+	target := testTargetMatrix(t).AllBuildTargets()[0]
+	config := BuildConfig{
+		Target:         target,
+		BuildDir:       filepath.Join(sdkRoot, "build", target.BuildDir()),
+		ConfigureCmd:   []string{"/bin/bash", "-c", `echo "$OE_CMAKE_TOOLCHAIN_FILE" > configured`},
+		BuildCmd:       []string{"/bin/bash", "-c", `echo "$OE_CMAKE_TOOLCHAIN_FILE" > built`},
+		EnvSetupScript: script,
+	}
+	if err := runBuild(context.Background(), sdkRoot, config, 1); err != nil {
+		t.Fatalf("expected build to succeed, got error: %v", err)
+	}
+
+	for _, name := range []string{"configured", "built"} {
+		data, err := os.ReadFile(filepath.Join(sdkRoot, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if strings.TrimSpace(string(data)) != "/opt/yocto sdk/toolchain.cmake" {
+			t.Fatalf("expected %s step to see the setup environment, got %q", name, data)
+		}
+	}
+
+	logData, err := os.ReadFile(config.logPath())
+	if err != nil {
+		t.Fatalf("failed to read build log: %v", err)
+	}
+	if !strings.Contains(string(logData), "sourcing toolchain") {
+		t.Fatalf("expected setup script output in the build log, got:\n%s", logData)
+	}
+}
+
+// TestCaptureEnvironmentFailsForBrokenScript verifies that a setup script
+// that cannot be sourced fails the build instead of running with a partial
+// environment.
+func TestCaptureEnvironmentFailsForBrokenScript(t *testing.T) {
+	var log bytes.Buffer
+	if _, err := captureEnvironment(context.Background(), filepath.Join(t.TempDir(), "missing"), &log); err == nil {
+		t.Fatal("expected a missing setup script to fail")
+	}
+}
+
+// TestGetBuildConfigsSeparatesSteps verifies that each configuration carries
+// its build directory and a build command targeting that directory, and that
+// only Yocto targets run inside a setup script environment.
+func TestGetBuildConfigsSeparatesSteps(t *testing.T) {
+	t.Setenv("MRS_SDK_QT_ROOT", "/tmp/mrs-sdk-root")
+
+	for _, config := range getBuildConfigs("/tmp/mrs sdk", testEnvConfig(), testBuildTools(), testTargetMatrix(t).AllBuildTargets()) {
+		expectedDir := filepath.Join("/tmp/mrs sdk", "build", config.Target.BuildDir())
+		if config.BuildDir != expectedDir {
+			t.Fatalf("expected build dir %q, got %q", expectedDir, config.BuildDir)
+		}
+		if i := slices.Index(config.ConfigureCmd, "-B"); i < 0 || config.ConfigureCmd[i+1] != expectedDir {
+			t.Fatalf("expected configure step to use %q, got %v", expectedDir, config.ConfigureCmd)
+		}
+		if !slices.Equal(config.BuildCmd, []string{"/usr/bin/cmake", "--build", expectedDir, "--target", "all"}) {
+			t.Fatalf("unexpected build step for %s: %v", config.Target.BuildDir(), config.BuildCmd)
+		}
+		if (config.EnvSetupScript != "") != (config.Target.OS == "yocto") {
+			t.Fatalf("unexpected setup script %q for %s", config.EnvSetupScript, config.Target.BuildDir())
+		}
+	}
+}
+
+// TestCMakeKitArgsOnlyEnablesQMLDebuggingForDebug verifies that QML
+// debugging is compiled into desktop debug builds only, and never into the
+// optimized release and relwithdebinfo libraries.
+func TestCMakeKitArgsOnlyEnablesQMLDebuggingForDebug(t *testing.T) {
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug", "release", "relwithdebinfo"}, TargetFilter{Targets: []string{"desktop-desktop-qt6"}})
+	if err != nil {
+		t.Fatalf("expected selection to succeed, got error: %v", err)
+	}
+	if len(targets) != 3 {
+		t.Fatalf("expected one target per build type, got %v", targets)
+	}
+
+	for _, target := range targets {
+		expected := "-DCMAKE_CXX_FLAGS_INIT:STRING="
+		if target.BuildType == "debug" {
+			expected += "-DQT_QML_DEBUG"
+		}
+		args := cmakeKitArgs(testEnvConfig(), target)
+		if !slices.Contains(args, expected) {
+			t.Fatalf("expected %s to pass %q, got %v", target.BuildDir(), expected, args)
+		}
+	}
+}
+
+// TestFormatCommandQuotesUnsafeArguments verifies that logged commands can
+// be pasted back into a shell.
+func TestFormatCommandQuotesUnsafeArguments(t *testing.T) {
+	got := formatCommand([]string{"/usr/bin/cmake", "-B", "/tmp/my build", "-DX:STRING=it's"})
+	expected := `/usr/bin/cmake -B '/tmp/my build' '-DX:STRING=it'\''s'`
+	if got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
@@ -10,6 +10,7 @@ const (
 	BuildScopeAll   BuildScope = "all"
 	BuildScopeLibs  BuildScope = "libs"
 	BuildScopeDemos BuildScope = "demos"
+	BuildScopeTest  BuildScope = "test"
 )
 
 // ParseBuildScope validates the optional positional target argument accepted by
@@ -22,10 +23,10 @@ func ParseBuildScope(raw string) (BuildScope, error) {
 
 	scope := BuildScope(raw)
 	switch scope {
-	case BuildScopeAll, BuildScopeLibs, BuildScopeDemos:
+	case BuildScopeAll, BuildScopeLibs, BuildScopeDemos, BuildScopeTest:
 		return scope, nil
 	default:
-		return "", fmt.Errorf("invalid build target %q (expected one of: all, libs, demos)", raw)
+		return "", fmt.Errorf("invalid build target %q (expected one of: all, libs, demos, test)", raw)
 	}
 }
 
@@ -40,3 +41,9 @@ func (scope BuildScope) IncludesLibs() bool {
 func (scope BuildScope) IncludesDemos() bool {
 	return scope == BuildScopeAll || scope == BuildScopeDemos
 }
+
+// IncludesTests reports whether the selected scope should build and run the
+// SDK unit tests. Tests are never part of the default scope.
+func (scope BuildScope) IncludesTests() bool {
+	return scope == BuildScopeTest
+}
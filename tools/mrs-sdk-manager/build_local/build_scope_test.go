@@ -22,6 +22,7 @@ func TestParseBuildScopeAcceptsSupportedTargets(t *testing.T) {
 		BuildScopeAll,
 		BuildScopeLibs,
 		BuildScopeDemos,
+		BuildScopeTest,
 	}
 
 	for _, testCase := range testCases {
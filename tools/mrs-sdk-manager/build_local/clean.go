@@ -0,0 +1,195 @@
+package buildlocal
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mrs-sdk-manager/utils"
+
+	"github.com/fatih/color"
+)
+
+// cacheFileNames are the files and directories removed from a build
+// directory by `clean --cache-only`. Removing the stamp makes the next
+// build-local run configure the target again instead of skipping it.
+var cacheFileNames = []string{cmakeCacheFileName, "CMakeFiles", stampFileName}
+
+// Clean removes the build directories of the given targets, or with all the
+// whole build directory. Targets are named by build directory, or by target
+// name to select all of its build types. With cacheOnly only the CMake cache
+// and generated files are removed, so the next build configures the target
+// from scratch. The test and demo builds of a target are cleaned with it.
+func Clean(sdkRoot string, targetArgs []string, all, cacheOnly bool) error {
+	if all == (len(targetArgs) > 0) {
+		return fmt.Errorf("specify the targets to clean or --all")
+	}
+
+	matrix, err := LoadTargetMatrix(sdkRoot)
+	if err != nil {
+		return err
+	}
+
+	var targets []BuildTarget
+	if !all {
+		if targets, err = matrix.NamedBuildTargets(targetArgs); err != nil {
+			return err
+		}
+	}
+
+	paths, err := cleanPaths(sdkRoot, targets, all, cacheOnly)
+	if err != nil {
+		return err
+	}
+
+	utils.PrintTaskStart("Cleaning build directories...")
+
+	var total int64
+	for _, path := range paths {
+		size, err := removeInsideBuildDir(sdkRoot, path)
+		if err != nil {
+			return err
+		}
+		total += size
+		color.White("Removed %s (%s)", path, formatSize(size))
+	}
+
+	if len(paths) == 0 {
+		color.White("Nothing to clean")
+	}
+	utils.PrintSuccess(fmt.Sprintf("Reclaimed %s", formatSize(total)))
+	return nil
+}
+
+// cleanPaths returns the existing paths that clean removes.
+func cleanPaths(sdkRoot string, targets []BuildTarget, all, cacheOnly bool) ([]string, error) {
+	buildRoot := filepath.Join(sdkRoot, "build")
+
+	var dirs []string
+	switch {
+	case all && !cacheOnly:
+		dirs = []string{buildRoot}
+	case all:
+		// Presets and demo builds live in their own subdirectories, so look
+		// for every CMake cache instead of deriving the directories.
+		err := filepath.WalkDir(buildRoot, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if !d.IsDir() && d.Name() == cmakeCacheFileName {
+				dirs = append(dirs, filepath.Dir(path))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", buildRoot, err)
+		}
+	default:
+		demoDirs, err := filepath.Glob(filepath.Join(buildRoot, "demos", "*"))
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range targets {
+			dirs = append(dirs,
+				filepath.Join(buildRoot, target.BuildDir()),
+				filepath.Join(buildRoot, "tests", target.BuildDir()))
+			for _, demoDir := range demoDirs {
+				dirs = append(dirs, filepath.Join(demoDir, target.BuildDir()))
+			}
+		}
+	}
+
+	var paths []string
+	for _, dir := range dirs {
+		candidates := []string{dir}
+		if cacheOnly {
+			candidates = nil
+			for _, name := range cacheFileNames {
+				candidates = append(candidates, filepath.Join(dir, name))
+			}
+		}
+		for _, path := range candidates {
+			if _, err := os.Lstat(path); err == nil {
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// removeInsideBuildDir removes path after checking that it is inside the
+// repository's build directory, also after resolving symlinks, and returns
+// the size of the removed files.
+func removeInsideBuildDir(sdkRoot, path string) (int64, error) {
+	buildRoot := filepath.Join(sdkRoot, "build")
+	if !isInsideDir(buildRoot, path) {
+		return 0, fmt.Errorf("refusing to remove %s: it is outside %s", path, buildRoot)
+	}
+
+	// Symlinks themselves are removed, not followed, so only the directory
+	// that contains path has to be resolved.
+	if path != buildRoot {
+		resolvedRoot, err := filepath.EvalSymlinks(buildRoot)
+		if err != nil {
+			return 0, fmt.Errorf("failed to resolve %s: %w", buildRoot, err)
+		}
+		resolvedParent, err := filepath.EvalSymlinks(filepath.Dir(path))
+		if err != nil {
+			return 0, fmt.Errorf("failed to resolve %s: %w", filepath.Dir(path), err)
+		}
+		if !isInsideDir(resolvedRoot, resolvedParent) {
+			return 0, fmt.Errorf("refusing to remove %s: it resolves to %s, outside %s", path, resolvedParent, buildRoot)
+		}
+	}
+
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure %s: %w", path, err)
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		return 0, fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return size, nil
+}
+
+// isInsideDir reports whether path is dir or below it.
+func isInsideDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// formatSize formats a byte count with a binary unit, e.g. "1.5 MiB".
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / unit
+	for _, suffix := range []string{"KiB", "MiB", "GiB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f TiB", value)
+}
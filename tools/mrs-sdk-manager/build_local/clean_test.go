@@ -0,0 +1,93 @@
+package buildlocal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestCleanPathsCoversTestAndDemoBuilds verifies that cleaning a target by
+// name selects every build type, including its test and demo builds, and
+// that --cache-only keeps everything but the CMake cache files.
+func TestCleanPathsCoversTestAndDemoBuilds(t *testing.T) {
+	repoRoot := t.TempDir()
+	buildRoot := filepath.Join(repoRoot, "build")
+	for _, dir := range []string{"fusion-buildroot-qt5-debug", "fusion-buildroot-qt5-release", "tests/fusion-buildroot-qt5-debug", "demos/hello/fusion-buildroot-qt5-debug", "mconn-yocto-qt5-debug"} {
+		writeTestFile(t, filepath.Join(buildRoot, dir, cmakeCacheFileName), "cache")
+		writeTestFile(t, filepath.Join(buildRoot, dir, "artifacts", "libmrs-sdk-qt.a"), "lib")
+	}
+
+	targets, err := testTargetMatrix(t).NamedBuildTargets([]string{"fusion-buildroot-qt5"})
+	if err != nil {
+		t.Fatalf("NamedBuildTargets returned error: %v", err)
+	}
+
+	paths, err := cleanPaths(repoRoot, targets, false, false)
+	if err != nil {
+		t.Fatalf("cleanPaths returned error: %v", err)
+	}
+	expected := []string{
+		filepath.Join(buildRoot, "fusion-buildroot-qt5-debug"),
+		filepath.Join(buildRoot, "tests", "fusion-buildroot-qt5-debug"),
+		filepath.Join(buildRoot, "demos", "hello", "fusion-buildroot-qt5-debug"),
+		filepath.Join(buildRoot, "fusion-buildroot-qt5-release"),
+	}
+	if !slices.Equal(paths, expected) {
+		t.Fatalf("expected paths %v, got %v", expected, paths)
+	}
+
+	paths, err = cleanPaths(repoRoot, nil, true, true)
+	if err != nil {
+		t.Fatalf("cleanPaths returned error: %v", err)
+	}
+	if len(paths) != 5 {
+		t.Fatalf("expected the cache of every build directory, got %v", paths)
+	}
+	for _, path := range paths {
+		if filepath.Base(path) != cmakeCacheFileName {
+			t.Fatalf("expected only cache files with --cache-only, got %s", path)
+		}
+	}
+
+	if _, err := testTargetMatrix(t).NamedBuildTargets([]string{"../lib"}); err == nil {
+		t.Fatal("expected an unknown target to be rejected")
+	}
+}
+
+// TestRemoveInsideBuildDirRefusesEscapes verifies that clean never removes
+// anything outside the build directory, also through symlinks, and reports
+// the size of what it removed.
+func TestRemoveInsideBuildDirRefusesEscapes(t *testing.T) {
+	repoRoot := t.TempDir()
+	outside := t.TempDir()
+	writeTestFile(t, filepath.Join(outside, "mconn-yocto-qt5-debug", "keep.txt"), "keep")
+	writeTestFile(t, filepath.Join(repoRoot, "build", "desktop-desktop-qt6-debug", "libmrs-sdk-qt.a"), "0123456789")
+	if err := os.Symlink(outside, filepath.Join(repoRoot, "build", "demos")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	for _, path := range []string{
+		filepath.Join(repoRoot, "lib"),
+		filepath.Join(repoRoot, "build", "..", "lib"),
+		filepath.Join(repoRoot, "build", "demos", "mconn-yocto-qt5-debug"),
+	} {
+		if _, err := removeInsideBuildDir(repoRoot, path); err == nil {
+			t.Fatalf("expected %s to be refused", path)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outside, "mconn-yocto-qt5-debug", "keep.txt")); err != nil {
+		t.Fatalf("expected files behind the symlink to be kept: %v", err)
+	}
+
+	size, err := removeInsideBuildDir(repoRoot, filepath.Join(repoRoot, "build", "desktop-desktop-qt6-debug"))
+	if err != nil {
+		t.Fatalf("removeInsideBuildDir returned error: %v", err)
+	}
+	if size != 10 {
+		t.Fatalf("expected 10 bytes to be reclaimed, got %d", size)
+	}
+	if _, err := os.Stat(filepath.Join(repoRoot, "build", "desktop-desktop-qt6-debug")); !os.IsNotExist(err) {
+		t.Fatalf("expected the build directory to be removed, got %v", err)
+	}
+}
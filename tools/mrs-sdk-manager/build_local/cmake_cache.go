@@ -0,0 +1,127 @@
+package buildlocal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cmakeCacheFileName is the cache CMake writes into every build directory.
+const cmakeCacheFileName = "CMakeCache.txt"
+
+// reconfigureCacheKeys are the cache entries whose change cannot be applied
+// to an existing build directory. CMake keeps the generator and compiler of
+// the first configure and ignores new ones, and find_package results such as
+// Qt5_DIR keep pointing into the old prefix or sysroot.
+var reconfigureCacheKeys = []string{
+	"CMAKE_GENERATOR",
+	"CMAKE_CXX_COMPILER",
+	"CMAKE_PREFIX_PATH",
+	"CMAKE_SYSROOT",
+	"CMAKE_TOOLCHAIN_FILE",
+}
+
+// readCMakeCache returns the values of a CMakeCache.txt by entry name.
+func readCMakeCache(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cache := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		// Entries are NAME:TYPE=VALUE, with the name quoted when it contains
+		// a colon or spaces.
+		var name, rest string
+		if quoted, ok := strings.CutPrefix(line, `"`); ok {
+			var found bool
+			if name, rest, found = strings.Cut(quoted, `"`); !found {
+				continue
+			}
+		} else {
+			var found bool
+			if name, rest, found = strings.Cut(line, ":"); !found {
+				continue
+			}
+		}
+		if _, value, found := strings.Cut(rest, "="); found {
+			cache[name] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+// staleCacheReason compares the CMake cache in the configuration's build
+// directory with the cache entries its configure step passes, and describes
+// every difference that requires configuring from scratch. It returns an
+// empty string when there is no cache or the cache can be reused.
+func staleCacheReason(config BuildConfig) (string, error) {
+	cachePath := filepath.Join(config.BuildDir, cmakeCacheFileName)
+	cache, err := readCMakeCache(cachePath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", cachePath, err)
+	}
+
+	planned := map[string]string{}
+	for _, arg := range config.ConfigureCmd {
+		if strings.HasPrefix(arg, "-D") {
+			name, variable := parseCacheArg(arg)
+			planned[name] = variable.Value
+		}
+	}
+
+	var reasons []string
+	for _, key := range reconfigureCacheKeys {
+		want, ok := planned[key]
+		if !ok || sameCacheValue(key, cache[key], want) {
+			continue
+		}
+		reasons = append(reasons, fmt.Sprintf("%s changed from %q to %q", key, cache[key], want))
+	}
+
+	return strings.Join(reasons, "; "), nil
+}
+
+// sameCacheValue reports whether a cached value matches the planned one.
+// CMake replaces a compiler given by name with its full path.
+func sameCacheValue(key, cached, planned string) bool {
+	if cached == planned {
+		return true
+	}
+	if cached == "" || planned == "" || key == "CMAKE_GENERATOR" {
+		return false
+	}
+	if key == "CMAKE_CXX_COMPILER" && !filepath.IsAbs(planned) {
+		return filepath.Base(cached) == planned
+	}
+	return filepath.Clean(cached) == filepath.Clean(planned)
+}
+
+// removeCMakeCache deletes the cache and CMake's generated files from a build
+// directory so that the next configure starts from scratch. Build outputs and
+// the build log are kept.
+func removeCMakeCache(buildDir string) error {
+	for _, name := range []string{cmakeCacheFileName, "CMakeFiles"} {
+		if err := os.RemoveAll(filepath.Join(buildDir, name)); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package buildlocal
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestStaleCacheReasonDetectsKitChanges verifies that a build directory is
+// only configured from scratch when the compiler, prefix, sysroot, toolchain
+// or generator in its CMake cache differ from the planned ones.
+func TestStaleCacheReasonDetectsKitChanges(t *testing.T) {
+	repoRoot := t.TempDir()
+	var desktop BuildTarget
+	for _, target := range testTargetMatrix(t).AllBuildTargets() {
+		if target.BuildDir() == "desktop-desktop-qt5-debug" {
+			desktop = target
+		}
+	}
+	config := newLibBuildConfig(repoRoot, testEnvConfig(), testBuildTools(), desktop, filepath.Join(repoRoot, "build", desktop.BuildDir()))
+
+	reason, err := staleCacheReason(config)
+	if err != nil || reason != "" {
+		t.Fatalf("expected a missing cache to need no reconfigure, got %q, %v", reason, err)
+	}
+
+	toolchain := filepath.Join(repoRoot, "lib/cmake/mrs-sdk-qt/toolchains", desktop.Toolchain+".cmake")
+	cache := strings.Join([]string{
+		"# This is the CMakeCache file.",
+		"//Path to a program.",
+		"CMAKE_CXX_COMPILER:FILEPATH=/usr/bin/g++",
+		"CMAKE_GENERATOR:INTERNAL=Ninja",
+		"CMAKE_PREFIX_PATH:PATH=/opt/Qt/5/",
+		"CMAKE_TOOLCHAIN_FILE:STRING=" + toolchain,
+		`"QUOTED:NAME":STRING=value`,
+		"",
+	}, "\n")
+	writeTestFile(t, filepath.Join(config.BuildDir, cmakeCacheFileName), cache)
+	if reason, err := staleCacheReason(config); err != nil || reason != "" {
+		t.Fatalf("expected a matching cache to be reused, got %q, %v", reason, err)
+	}
+
+	writeTestFile(t, filepath.Join(config.BuildDir, cmakeCacheFileName), strings.NewReplacer(
+		"/opt/Qt/5/", "/opt/Qt/5.15.2/gcc_64",
+		"=Ninja", "=Unix Makefiles",
+	).Replace(cache))
+	reason, err = staleCacheReason(config)
+	if err != nil {
+		t.Fatalf("staleCacheReason returned error: %v", err)
+	}
+	expected := `CMAKE_GENERATOR changed from "Unix Makefiles" to "Ninja"; CMAKE_PREFIX_PATH changed from "/opt/Qt/5.15.2/gcc_64" to "/opt/Qt/5"`
+	if reason != expected {
+		t.Fatalf("expected reason %q, got %q", expected, reason)
+	}
+}
+
+// TestRunAllBuildsRemovesStaleCache verifies that a stale CMake cache is
+// removed before the configure step, while build outputs are kept.
+func TestRunAllBuildsRemovesStaleCache(t *testing.T) {
+	repoRoot := t.TempDir()
+	target := testTargetMatrix(t).AllBuildTargets()[0]
+	buildDir := filepath.Join(repoRoot, "build", target.BuildDir())
+	writeTestFile(t, filepath.Join(buildDir, cmakeCacheFileName), "CMAKE_CXX_COMPILER:STRING=/old/arm-g++\n")
+	writeTestFile(t, filepath.Join(buildDir, "CMakeFiles", "3.28.3", "CMakeCXXCompiler.cmake"), "old")
+	writeTestFile(t, filepath.Join(buildDir, "libmrs-sdk-qt.a"), "old")
+
+	configs := []BuildConfig{{
+		Target:   target,
+		BuildDir: buildDir,
+		ConfigureCmd: []string{"/bin/bash", "-c", `test ! -e "$1/CMakeCache.txt" && test ! -e "$1/CMakeFiles"`, "bash", buildDir,
+			"-DCMAKE_CXX_COMPILER:STRING=/new/arm-g++"},
+		BuildCmd: []string{"true"},
+	}}
+	err := runAllBuilds(context.Background(), repoRoot, configs, 1, 1, false, newProgressReporter(OutputPlain, io.Discard))
+	if err != nil {
+		t.Fatalf("expected the configure step to find no cache, got error: %v", err)
+	}
+	assertFileExists(t, filepath.Join(buildDir, "libmrs-sdk-qt.a"))
+}
@@ -0,0 +1,234 @@
+package buildlocal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"mrs-sdk-manager/env"
+	"mrs-sdk-manager/utils"
+
+	"github.com/fatih/color"
+)
+
+// compileCommandsFileName is the JSON compilation database CMake exports into
+// each build directory.
+const compileCommandsFileName = "compile_commands.json"
+
+// compileCommand is one entry of a JSON compilation database.
+type compileCommand struct {
+	Directory string   `json:"directory"`
+	File      string   `json:"file"`
+	Arguments []string `json:"arguments,omitempty"`
+	Command   string   `json:"command,omitempty"`
+	Output    string   `json:"output,omitempty"`
+}
+
+// processorTriples are the clang target triples used for processors whose
+// matrix entry has no compiler_target.
+var processorTriples = map[string]string{
+	"arm":     "arm-linux-gnueabihf",
+	"aarch64": "aarch64-linux-gnu",
+}
+
+// WriteCompileCommands writes compile_commands.json to the repository root
+// from the databases of the given targets, so clangd indexes lib/ with the
+// defines and headers of those targets. Targets are named like in `logs`.
+// Without targets the most recently built target is used. When several
+// targets compile the same file, the first target given wins.
+func WriteCompileCommands(sdkRoot string, targetArgs []string) error {
+	matrix, err := LoadTargetMatrix(sdkRoot)
+	if err != nil {
+		return err
+	}
+
+	envConfig, err := env.ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to read environment config: %w", err)
+	}
+
+	if len(targetArgs) == 0 {
+		targetArgs = []string{""}
+	}
+	var targets []BuildTarget
+	var names []string
+	for _, targetArg := range targetArgs {
+		target, err := resolveTargetFile(filepath.Join(sdkRoot, "build"), matrix, compileCommandsFileName, "compilation database", targetArg)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(names, target.BuildDir()) {
+			targets = append(targets, target)
+			names = append(names, target.BuildDir())
+		}
+	}
+
+	utils.PrintTaskStart(fmt.Sprintf("Merging compilation databases of %s...", strings.Join(names, ", ")))
+
+	commands, err := mergeCompileCommands(sdkRoot, envConfig, targets)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(commands, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", compileCommandsFileName, err)
+	}
+	outPath := filepath.Join(sdkRoot, compileCommandsFileName)
+	if err := os.WriteFile(outPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outPath, err)
+	}
+
+	color.White("Wrote %d entries to %s", len(commands), outPath)
+	utils.PrintSuccess("Compilation database updated")
+	return nil
+}
+
+// mergeCompileCommands reads the databases of the targets in order and keeps
+// the first entry for each source file. Every entry is rewritten for clangd.
+func mergeCompileCommands(sdkRoot string, envConfig map[string]string, targets []BuildTarget) ([]compileCommand, error) {
+	var merged []compileCommand
+	seen := map[string]bool{}
+	for _, target := range targets {
+		path := filepath.Join(sdkRoot, "build", target.BuildDir(), compileCommandsFileName)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		var commands []compileCommand
+		if err := json.Unmarshal(data, &commands); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		for _, command := range commands {
+			file := command.File
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(command.Directory, file)
+			}
+			if seen[file] {
+				continue
+			}
+			seen[file] = true
+
+			args := command.Arguments
+			if len(args) == 0 {
+				if args, err = splitCommand(command.Command); err != nil {
+					return nil, fmt.Errorf("%s: entry for %s: %w", path, command.File, err)
+				}
+			}
+			if len(args) == 0 {
+				return nil, fmt.Errorf("%s: entry for %s has no command", path, command.File)
+			}
+
+			merged = append(merged, compileCommand{
+				Directory: command.Directory,
+				File:      command.File,
+				Arguments: clangdArgs(args, target, envConfig),
+				Output:    command.Output,
+			})
+		}
+	}
+
+	return merged, nil
+}
+
+// clangdArgs rewrites a cross-compiler command line so that clangd parses it
+// for the target: any target flag is replaced with an explicit --target, and
+// --sysroot is added for compilers that only have it built in.
+func clangdArgs(args []string, target BuildTarget, envConfig map[string]string) []string {
+	if target.OS == "desktop" {
+		return args
+	}
+
+	var rewritten []string
+	hasSysroot := false
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-target" || arg == "--target":
+			i++
+			continue
+		case strings.HasPrefix(arg, "--target="):
+			continue
+		case arg == "--sysroot" && i+1 < len(args):
+			i++
+			arg = "--sysroot=" + args[i]
+		}
+		if strings.HasPrefix(arg, "--sysroot=") {
+			hasSysroot = true
+		}
+		rewritten = append(rewritten, arg)
+	}
+
+	triple := target.CompilerTarget
+	if triple == "" {
+		triple = processorTriples[target.Processor]
+		if target.Processor == "arm" && target.FloatABI == "soft" {
+			triple = "arm-linux-gnueabi"
+		}
+	}
+
+	prefix := []string{args[0], "--target=" + triple}
+	if sysroot := envConfig[target.Env.Sysroot]; !hasSysroot && sysroot != "" {
+		prefix = append(prefix, "--sysroot="+sysroot)
+	}
+	return append(prefix, rewritten...)
+}
+
+// splitCommand splits a command line the way a POSIX shell would, which is
+// how CMake quotes the "command" field of a compilation database.
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		case c == '\\':
+			if i+1 >= len(command) {
+				return nil, fmt.Errorf("trailing backslash in command")
+			}
+			i++
+			current.WriteByte(command[i])
+			inArg = true
+		case c == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote in command")
+			}
+			current.WriteString(command[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+		case c == '"':
+			i++
+			for ; i < len(command) && command[i] != '"'; i++ {
+				if command[i] == '\\' && i+1 < len(command) && strings.IndexByte("\"\\$`", command[i+1]) >= 0 {
+					i++
+				}
+				current.WriteByte(command[i])
+			}
+			if i >= len(command) {
+				return nil, fmt.Errorf("unterminated double quote in command")
+			}
+			inArg = true
+		default:
+			current.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
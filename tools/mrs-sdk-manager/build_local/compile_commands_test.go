@@ -0,0 +1,68 @@
+package buildlocal
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestMergeCompileCommandsRewritesCrossTargets verifies that the first target
+// wins for files compiled by several targets, and that cross-compiler entries
+// get the target triple and sysroot that clangd needs.
+func TestMergeCompileCommandsRewritesCrossTargets(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeTestFile(t, filepath.Join(repoRoot, "build", "fusion-buildroot-qt5-debug", compileCommandsFileName), `[
+  {"directory": "/repo/build/fusion", "file": "/repo/lib/src/device.cpp", "command": "/tmp/buildroot/bin/arm-g++ -DMRS_SDK_QT_DEVICE_FUSION -I\"/repo/lib/include dir\" -o device.o -c /repo/lib/src/device.cpp"}
+]`)
+	writeTestFile(t, filepath.Join(repoRoot, "build", "mconn-yocto-qt5-debug", compileCommandsFileName), `[
+  {"directory": "/repo/build/mconn", "file": "/repo/lib/src/device.cpp", "arguments": ["/tmp/yocto/bin/arm-g++", "-DMRS_SDK_QT_DEVICE_MCONN", "-c", "/repo/lib/src/device.cpp"]},
+  {"directory": "/repo/build/mconn", "file": "../../lib/src/mconn.cpp", "arguments": ["/tmp/yocto/bin/arm-g++", "-target", "armv7", "--sysroot", "/opt/sysroot", "-c", "../../lib/src/mconn.cpp"]}
+]`)
+
+	var targets []BuildTarget
+	for _, name := range []string{"fusion-buildroot-qt5-debug", "mconn-yocto-qt5-debug"} {
+		target, err := resolveTargetFile(filepath.Join(repoRoot, "build"), testTargetMatrix(t), compileCommandsFileName, "compilation database", name)
+		if err != nil {
+			t.Fatalf("failed to resolve %s: %v", name, err)
+		}
+		targets = append(targets, target)
+	}
+
+	commands, err := mergeCompileCommands(repoRoot, testEnvConfig(), targets)
+	if err != nil {
+		t.Fatalf("mergeCompileCommands returned error: %v", err)
+	}
+	if len(commands) != 2 {
+		t.Fatalf("expected one entry per source file, got %+v", commands)
+	}
+
+	expected := []string{"/tmp/buildroot/bin/arm-g++", "--target=arm-linux-gnueabihf", "--sysroot=/tmp/buildroot/sysroot",
+		"-DMRS_SDK_QT_DEVICE_FUSION", "-I/repo/lib/include dir", "-o", "device.o", "-c", "/repo/lib/src/device.cpp"}
+	if !slices.Equal(commands[0].Arguments, expected) {
+		t.Fatalf("expected the FUSION entry to win with arguments %q, got %q", expected, commands[0].Arguments)
+	}
+
+	expected = []string{"/tmp/yocto/bin/arm-g++", "--target=arm-poky-linux-gnueabi", "--sysroot=/opt/sysroot", "-c", "../../lib/src/mconn.cpp"}
+	if !slices.Equal(commands[1].Arguments, expected) {
+		t.Fatalf("expected arguments %q, got %q", expected, commands[1].Arguments)
+	}
+}
+
+// TestSplitCommandFollowsShellQuoting verifies that the "command" field is
+// split like a POSIX shell would split it.
+func TestSplitCommandFollowsShellQuoting(t *testing.T) {
+	args, err := splitCommand(`g++  -DNAME="\"quoted\"" '-DPATH=/a b' -I/c\ d -c x.cpp`)
+	if err != nil {
+		t.Fatalf("splitCommand returned error: %v", err)
+	}
+	expected := []string{"g++", `-DNAME="quoted"`, "-DPATH=/a b", "-I/c d", "-c", "x.cpp"}
+	if !slices.Equal(args, expected) {
+		t.Fatalf("expected %q, got %q", expected, args)
+	}
+
+	for _, command := range []string{`g++ "-c`, `g++ '-c`, `g++ -c\`} {
+		if _, err := splitCommand(command); err == nil {
+			t.Fatalf("expected %q to be rejected", command)
+		}
+	}
+}
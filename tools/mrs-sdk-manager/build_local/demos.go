@@ -0,0 +1,241 @@
+package buildlocal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mrs-sdk-manager/manifest"
+	"mrs-sdk-manager/use"
+	"mrs-sdk-manager/utils"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+)
+
+// demoProject is an application under demos/ that is built against the
+// installed SDK to check that consumers still compile.
+type demoProject struct {
+	Name    string // Directory name, e.g. "can-simulator"
+	Dir     string // Absolute source directory
+	ProFile string // QMake project file; empty for CMake projects
+}
+
+// discoverDemos lists the demo projects in the repository. Projects with both
+// a CMakeLists.txt and a .pro file are built with CMake.
+func discoverDemos(sdkRoot string) ([]demoProject, error) {
+	demosRoot := filepath.Join(sdkRoot, "demos")
+	entries, err := os.ReadDir(demosRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read demos directory: %w", err)
+	}
+
+	var demos []demoProject
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		demo := demoProject{Name: entry.Name(), Dir: filepath.Join(demosRoot, entry.Name())}
+		hasCMake, hasQMake := use.DetectBuildSystems(demo.Dir)
+		if !hasCMake && !hasQMake {
+			continue
+		}
+		if !hasCMake {
+			proFiles, err := filepath.Glob(filepath.Join(demo.Dir, "*.pro"))
+			if err != nil || len(proFiles) != 1 {
+				return nil, fmt.Errorf("expected exactly one .pro file in %s", demo.Dir)
+			}
+			demo.ProFile = proFiles[0]
+		}
+		demos = append(demos, demo)
+	}
+
+	return demos, nil
+}
+
+// planDemoStage plans the builds of every demo for the selected targets
+// against the installed copy of this repository's SDK version, using the same
+// project files that `mrs-sdk-manager use` generates for SDK consumers. The
+// stage's prepare step installs the libraries when installLibs and
+// opts.Install are set, installs the demo sources with opts.Install, and
+// generates those project files.
+func planDemoStage(sdkRoot string, envConfig map[string]string, tools buildTools, targets []BuildTarget, opts Options, installLibs bool) (buildStage, error) {
+	sdkInstallRoot, err := utils.ResolveSDKInstallRoot()
+	if err != nil {
+		return buildStage{}, err
+	}
+
+	sdkVersion := utils.ResolveSDKVersion(sdkRoot)
+	if !opts.Install {
+		versionRoot := filepath.Join(sdkInstallRoot, sdkVersion)
+		if _, err := os.Stat(versionRoot); err != nil {
+			return buildStage{}, fmt.Errorf("SDK version %s is not installed in %s; run 'mrs-sdk-manager build-local libs --install' first", sdkVersion, sdkInstallRoot)
+		}
+
+		// Without --install the demos silently use whatever is installed,
+		// which need not be what was last built from this checkout.
+		problems, err := staleInstallProblems(sdkRoot, versionRoot, targets)
+		if err != nil {
+			return buildStage{}, err
+		}
+		if len(problems) > 0 {
+			color.Yellow("Warning: the installed SDK version %s does not match this checkout, so the demos are not built against the current libraries:", sdkVersion)
+			for _, problem := range problems {
+				color.Yellow("  %s", problem)
+			}
+			color.Yellow("Pass --install, or run 'mrs-sdk-manager build-local libs --install' first, to build them against the current libraries.")
+		}
+	}
+
+	demos, err := discoverDemos(sdkRoot)
+	if err != nil {
+		return buildStage{}, err
+	}
+
+	utils.PrintTaskStart(fmt.Sprintf("Building demos against SDK version %s...", sdkVersion))
+	configs := getDemoBuildConfigs(sdkRoot, envConfig, tools, demos, targets)
+	concurrency, jobsPerTarget := jobBudget(opts.Jobs, opts.ParallelTargets, len(configs))
+	color.White("Building up to %d demo(s) at a time with %d job(s) each", concurrency, jobsPerTarget)
+
+	prepare := func() error {
+		if opts.Install {
+			if installLibs {
+				if err := InstallBuilds(sdkRoot, targets); err != nil {
+					return err
+				}
+			}
+			if err := InstallDemoSources(sdkRoot); err != nil {
+				return err
+			}
+		}
+		for _, demo := range demos {
+			if err := use.WriteProjectConfig(demo.Dir, sdkVersion); err != nil {
+				return fmt.Errorf("failed to configure %s: %w", demo.Name, err)
+			}
+		}
+		return nil
+	}
+
+	return buildStage{configs: configs, concurrency: concurrency, jobsPerTarget: jobsPerTarget, prepare: prepare}, nil
+}
+
+// staleInstallProblems describes how the SDK version installed at versionRoot
+// differs from this checkout: it was installed from another commit, or the
+// library of a target differs from the one last built in build/. Targets that
+// have not been built are not compared.
+func staleInstallProblems(sdkRoot, versionRoot string, targets []BuildTarget) ([]string, error) {
+	var problems []string
+
+	current := resolveBuildInfo(sdkRoot)
+	installManifest, err := manifest.Read(versionRoot)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		problems = append(problems, fmt.Sprintf("the installation has no %s, so its commit is unknown", manifest.FileName))
+	case err != nil:
+		return nil, err
+	case current.Commit != "" && installManifest.Commit != current.Commit:
+		problems = append(problems, fmt.Sprintf("installed from commit %s, but %s is checked out", shortCommit(installManifest.Commit), shortCommit(current.Commit)))
+	}
+
+	for _, target := range targets {
+		lib := libraryInstallFile(target, sdkRoot, versionRoot)
+		built, err := os.ReadFile(lib.Src)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		installed, err := os.ReadFile(lib.Dst)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			problems = append(problems, fmt.Sprintf("%s: the library is not installed", target.BuildDir()))
+		case err != nil:
+			return nil, err
+		case !bytes.Equal(built, installed):
+			problems = append(problems, fmt.Sprintf("%s: the installed library differs from the last build", target.BuildDir()))
+		}
+	}
+
+	return problems, nil
+}
+
+// getDemoBuildConfigs returns a build configuration for every demo and target
+// pair. Demos always rebuild, since they mainly check that the installed SDK
+// can be consumed.
+func getDemoBuildConfigs(sdkRoot string, envConfig map[string]string, tools buildTools, demos []demoProject, targets []BuildTarget) []BuildConfig {
+	var configs []BuildConfig
+	for _, target := range targets {
+		for _, demo := range demos {
+			buildDir := filepath.Join(sdkRoot, "build", "demos", demo.Name, target.BuildDir())
+			config := BuildConfig{
+				Target:   target,
+				Project:  demo.Name,
+				BuildDir: buildDir,
+			}
+			if target.OS == "yocto" {
+				config.EnvSetupScript = envConfig[target.Env.EnvSetupScript]
+			}
+
+			if demo.ProFile == "" {
+				config.ConfigureCmd = []string{tools.CMake.Path, "-S", demo.Dir, "-B", buildDir}
+				config.ConfigureCmd = append(config.ConfigureCmd, tools.generatorArgs()...)
+				config.ConfigureCmd = append(config.ConfigureCmd,
+					"-DCMAKE_TOOLCHAIN_FILE:FILEPATH="+filepath.Join(demo.Dir, "mrs-sdk-qt", "toolchain.cmake"),
+					"-DMRS_SDK_QT_TOOLCHAIN_ID:STRING="+target.Toolchain)
+				config.ConfigureCmd = append(config.ConfigureCmd, cmakeKitArgs(envConfig, target)...)
+				config.ConfigureCmd = append(config.ConfigureCmd, "-DMRS_SDK_QT_TARGET_DEVICE:STRING="+target.Device,
+					"-DCMAKE_BUILD_TYPE:STRING="+target.CMakeBuildType())
+				config.BuildCmd = []string{tools.CMake.Path, "--build", buildDir, "--target", "all"}
+			} else {
+				// toolchain.pri and config.pri read the kit settings from the
+				// environment, like a Qt Creator kit would provide them.
+				config.Env = []string{
+					"MRS_SDK_QT_TOOLCHAIN_ID=" + target.Toolchain,
+					"MRS_SDK_QT_TARGET_DEVICE=" + target.Device,
+				}
+				config.ConfigureCmd = append([]string{qmakePath(envConfig, target), demo.ProFile, "-o", filepath.Join(buildDir, "Makefile")},
+					qmakeConfigArgs(target)...)
+				if target.OS == "desktop" {
+					config.ConfigureCmd = append(config.ConfigureCmd, "QMAKE_CXX="+envConfig[target.Env.CXXCompiler],
+						"QMAKE_LINK="+envConfig[target.Env.CXXCompiler])
+				}
+				config.BuildCmd = []string{"make", "-C", buildDir}
+				config.JobsFlag = "-j"
+			}
+
+			configs = append(configs, config)
+		}
+	}
+
+	return configs
+}
+
+// qmakePath returns the qmake of the target's Qt installation. Yocto SDKs put
+// their qmake on the PATH of the setup script environment.
+func qmakePath(envConfig map[string]string, b BuildTarget) string {
+	switch b.OS {
+	case "buildroot":
+		// Buildroot installs its host tools next to the cross-compiler.
+		return filepath.Join(filepath.Dir(envConfig[b.Env.CXXCompiler]), "qmake")
+	case "desktop":
+		return filepath.Join(envConfig[b.Env.QtPrefix], "bin", "qmake")
+	default:
+		return "qmake"
+	}
+}
+
+// qmakeConfigArgs selects the QMake configuration that links the library of
+// the target's build type, mirroring the lookup in config.pri.
+func qmakeConfigArgs(b BuildTarget) []string {
+	switch b.BuildType {
+	case "release":
+		return []string{"CONFIG+=release", "CONFIG-=debug"}
+	case "relwithdebinfo":
+		return []string{"CONFIG+=release", "CONFIG+=force_debug_info", "CONFIG-=debug"}
+	default:
+		return []string{"CONFIG+=debug", "CONFIG-=release"}
+	}
+}
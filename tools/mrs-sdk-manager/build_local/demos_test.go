@@ -0,0 +1,173 @@
+package buildlocal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestDiscoverDemosFindsCMakeAndQMakeProjects verifies that every directory
+// under demos/ with a project file is picked up with the right build system,
+// and that other entries are ignored.
+func TestDiscoverDemosFindsCMakeAndQMakeProjects(t *testing.T) {
+	sdkRoot := t.TempDir()
+	writeTestFile(t, filepath.Join(sdkRoot, "demos", "README.md"), "# Demos\n")
+	writeTestFile(t, filepath.Join(sdkRoot, "demos", "cmake-demo", "CMakeLists.txt"), "project(cmake-demo)\n")
+	writeTestFile(t, filepath.Join(sdkRoot, "demos", "qmake-demo", "qmake-demo.pro"), "TEMPLATE = app\n")
+	writeTestFile(t, filepath.Join(sdkRoot, "demos", "notes", "todo.txt"), "nothing to build\n")
+
+	demos, err := discoverDemos(sdkRoot)
+	if err != nil {
+		t.Fatalf("expected discovery to succeed, got error: %v", err)
+	}
+	if len(demos) != 2 {
+		t.Fatalf("expected two demos, got %+v", demos)
+	}
+	if demos[0].Name != "cmake-demo" || demos[0].ProFile != "" {
+		t.Fatalf("expected cmake-demo to build with CMake, got %+v", demos[0])
+	}
+	if demos[1].Name != "qmake-demo" || demos[1].ProFile != filepath.Join(sdkRoot, "demos", "qmake-demo", "qmake-demo.pro") {
+		t.Fatalf("expected qmake-demo to build with QMake, got %+v", demos[1])
+	}
+}
+
+// TestGetDemoBuildConfigsUsesGeneratedProjectFiles verifies that demos are
+// configured through the `use` project files with the same kit as the
+// library, and that QMake demos receive the kit settings from the
+// environment.
+func TestGetDemoBuildConfigsUsesGeneratedProjectFiles(t *testing.T) {
+	sdkRoot := "/tmp/mrs-sdk-qt"
+	demos := []demoProject{
+		{Name: "cmake-demo", Dir: sdkRoot + "/demos/cmake-demo"},
+		{Name: "qmake-demo", Dir: sdkRoot + "/demos/qmake-demo", ProFile: sdkRoot + "/demos/qmake-demo/qmake-demo.pro"},
+	}
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"release"}, TargetFilter{Targets: []string{"desktop-desktop-qt6", "mconn-yocto-qt5"}})
+	if err != nil {
+		t.Fatalf("expected selection to succeed, got error: %v", err)
+	}
+
+	configs := getDemoBuildConfigs(sdkRoot, testEnvConfig(), testBuildTools(), demos, targets)
+	if len(configs) != len(demos)*len(targets) {
+		t.Fatalf("expected a config per demo and target, got %d", len(configs))
+	}
+
+	byLabel := map[string]BuildConfig{}
+	for _, config := range configs {
+		byLabel[config.label()] = config
+	}
+
+	cmakeDesktop := byLabel["cmake-demo/desktop-desktop-qt6-release"]
+	for _, arg := range []string{
+		"-DCMAKE_TOOLCHAIN_FILE:FILEPATH=/tmp/mrs-sdk-qt/demos/cmake-demo/mrs-sdk-qt/toolchain.cmake",
+		"-DMRS_SDK_QT_TOOLCHAIN_ID:STRING=desktop-qt6",
+		"-DCMAKE_PREFIX_PATH:PATH=/opt/Qt/6",
+		"-DCMAKE_BUILD_TYPE:STRING=Release",
+	} {
+		if !slices.Contains(cmakeDesktop.ConfigureCmd, arg) {
+			t.Fatalf("expected CMake demo configure args to include %q, got %s", arg, strings.Join(cmakeDesktop.ConfigureCmd, " "))
+		}
+	}
+	if cmakeDesktop.BuildDir != "/tmp/mrs-sdk-qt/build/demos/cmake-demo/desktop-desktop-qt6-release" {
+		t.Fatalf("unexpected demo build dir %q", cmakeDesktop.BuildDir)
+	}
+
+	qmakeDesktop := byLabel["qmake-demo/desktop-desktop-qt6-release"]
+	if qmakeDesktop.ConfigureCmd[0] != "/opt/Qt/6/bin/qmake" || !slices.Contains(qmakeDesktop.ConfigureCmd, "CONFIG+=release") {
+		t.Fatalf("unexpected QMake configure args %v", qmakeDesktop.ConfigureCmd)
+	}
+	if !slices.Contains(qmakeDesktop.Env, "MRS_SDK_QT_TOOLCHAIN_ID=desktop-qt6") || !slices.Contains(qmakeDesktop.Env, "MRS_SDK_QT_TARGET_DEVICE=desktop") {
+		t.Fatalf("expected QMake kit settings in the environment, got %v", qmakeDesktop.Env)
+	}
+	if qmakeDesktop.JobsFlag != "-j" {
+		t.Fatalf("expected make to receive the job limit with -j, got %q", qmakeDesktop.JobsFlag)
+	}
+
+	qmakeYocto := byLabel["qmake-demo/mconn-yocto-qt5-release"]
+	if qmakeYocto.ConfigureCmd[0] != "qmake" || qmakeYocto.EnvSetupScript != "/tmp/yocto/environment-setup" {
+		t.Fatalf("expected Yocto demos to use the qmake from the setup script environment, got %+v", qmakeYocto)
+	}
+}
+
+// TestPlanDemoStageRequiresInstalledVersion verifies that demos are not built
+// against an SDK version that has not been installed yet.
+func TestPlanDemoStageRequiresInstalledVersion(t *testing.T) {
+	sdkRoot := t.TempDir()
+	initTestRepo(t, sdkRoot)
+	t.Setenv("MRS_SDK_QT_ROOT", t.TempDir())
+
+	_, err := planDemoStage(sdkRoot, testEnvConfig(), testBuildTools(), testTargetMatrix(t).AllBuildTargets(), Options{Output: OutputPlain}, false)
+	if err == nil || !strings.Contains(err.Error(), "is not installed") {
+		t.Fatalf("expected an error about the missing SDK version, got %v", err)
+	}
+}
+
+// TestLookPathInUsesCapturedPath verifies that programs are resolved against
+// the PATH of a captured setup environment rather than our own.
+func TestLookPathInUsesCapturedPath(t *testing.T) {
+	binDir := t.TempDir()
+	writeTestFile(t, filepath.Join(binDir, "qmake"), "#!/bin/sh\n")
+	if err := os.Chmod(filepath.Join(binDir, "qmake"), 0755); err != nil {
+		t.Fatalf("failed to make fake qmake executable: %v", err)
+	}
+
+	if got := lookPathIn("qmake", []string{"PATH=/nonexistent:" + binDir}); got != filepath.Join(binDir, "qmake") {
+		t.Fatalf("expected qmake from the captured PATH, got %q", got)
+	}
+	if got := lookPathIn("qmake", nil); got != "qmake" {
+		t.Fatalf("expected our own environment to leave the name to os/exec, got %q", got)
+	}
+}
+
+// TestStaleInstallProblemsComparesWithLastBuild verifies that building the
+// demos without --install warns about an installation from another commit
+// and about installed libraries that differ from the last build.
+func TestStaleInstallProblemsComparesWithLastBuild(t *testing.T) {
+	repoRoot := t.TempDir()
+	sdkRoot := filepath.Join(t.TempDir(), "sdk")
+	t.Setenv("MRS_SDK_QT_ROOT", sdkRoot)
+	initTestRepo(t, repoRoot)
+	createFakeSDKRepo(t, repoRoot)
+	runGit(t, repoRoot, "commit", "-m", "Initial commit")
+
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{Targets: []string{"desktop-desktop-qt5", "desktop-desktop-qt6"}})
+	if err != nil {
+		t.Fatalf("expected selection to succeed, got error: %v", err)
+	}
+	if err := InstallBuilds(repoRoot, targets[:1]); err != nil {
+		t.Fatalf("InstallBuilds returned error: %v", err)
+	}
+	versionRoot := filepath.Join(sdkRoot, "0.0.0")
+
+	problems, err := staleInstallProblems(repoRoot, versionRoot, targets[:1])
+	if err != nil {
+		t.Fatalf("staleInstallProblems returned error: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Fatalf("expected a fresh installation to match, got %v", problems)
+	}
+
+	writeTestFile(t, libraryInstallFile(targets[0], repoRoot, "").Src, "rebuilt")
+	writeTestFile(t, filepath.Join(repoRoot, "README.md"), "changed")
+	runGit(t, repoRoot, "add", "README.md")
+	runGit(t, repoRoot, "commit", "-m", "Second commit")
+
+	problems, err = staleInstallProblems(repoRoot, versionRoot, targets)
+	if err != nil {
+		t.Fatalf("staleInstallProblems returned error: %v", err)
+	}
+	expected := []string{
+		"installed from commit",
+		"desktop-desktop-qt5-debug: the installed library differs from the last build",
+		"desktop-desktop-qt6-debug: the library is not installed",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %v", len(expected), problems)
+	}
+	for i, problem := range problems {
+		if !strings.HasPrefix(problem, expected[i]) {
+			t.Fatalf("expected %q, got %v", expected[i], problems)
+		}
+	}
+}
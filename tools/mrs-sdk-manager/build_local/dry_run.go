@@ -0,0 +1,199 @@
+package buildlocal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"mrs-sdk-manager/env"
+	"mrs-sdk-manager/utils"
+
+	"github.com/fatih/color"
+)
+
+// printDryRun prints what Run would do for the scope: the env values it
+// reads, the build tools it would use, every command of every build
+// configuration, and every file an install would copy. Nothing is built,
+// written or copied. The tools were located without running them, so their
+// versions are unknown; missing lists the ones that could not be found.
+func printDryRun(out io.Writer, sdkRoot string, envConfig map[string]string, tools buildTools, missing []error, scope BuildScope, targets []BuildTarget, opts Options) error {
+	utils.PrintTaskStart("Env config")
+	printDryRunEnv(out, envConfig, scope, targets)
+
+	utils.PrintTaskStart("Build tools (not run)")
+	tools.print(out)
+	for _, err := range missing {
+		fmt.Fprintf(out, "%s %v\n", color.YellowString("Not found:"), err)
+	}
+
+	if scope.IncludesLibs() {
+		utils.PrintTaskStart("SDK library builds")
+		configs, numOutdated, err := planLibraryBuilds(sdkRoot, envConfig, tools, targets, opts.Force)
+		if err != nil {
+			return err
+		}
+		if err := printBuildPlan(out, configs, numOutdated, opts); err != nil {
+			return err
+		}
+
+		if opts.Install {
+			utils.PrintTaskStart("SDK installation")
+			if err := printInstallPlan(out, sdkRoot, targets); err != nil {
+				return err
+			}
+		}
+	}
+
+	if scope.IncludesTests() {
+		utils.PrintTaskStart("Unit test builds")
+		configs := getTestBuildConfigs(sdkRoot, envConfig, tools, targets)
+		if err := printBuildPlan(out, configs, len(configs), opts); err != nil {
+			return err
+		}
+	}
+
+	if scope.IncludesDemos() {
+		if opts.Install {
+			utils.PrintTaskStart("Demo sources installation")
+			if err := printDemoSourcesPlan(out, sdkRoot); err != nil {
+				return err
+			}
+		}
+
+		utils.PrintTaskStart("Demo builds")
+		demos, err := discoverDemos(sdkRoot)
+		if err != nil {
+			return err
+		}
+		sdkVersion := utils.ResolveSDKVersion(sdkRoot)
+		for _, demo := range demos {
+			fmt.Fprintf(out, "Write the project config for SDK version %s to %s\n", sdkVersion, filepath.Join(demo.Dir, "mrs-sdk-qt"))
+		}
+		configs := getDemoBuildConfigs(sdkRoot, envConfig, tools, demos, targets)
+		if err := printBuildPlan(out, configs, len(configs), opts); err != nil {
+			return err
+		}
+	}
+
+	utils.PrintSuccess("Dry run complete; nothing was built or installed")
+	return nil
+}
+
+// printDryRunEnv prints the env config values read for the scope, including
+// optional keys that are not set.
+func printDryRunEnv(out io.Writer, envConfig map[string]string, scope BuildScope, targets []BuildTarget) {
+	var keys []string
+	for _, v := range requiredEnvVarsForScope(scope, targets) {
+		keys = append(keys, v.Key)
+	}
+	if scope.IncludesTests() {
+		for _, target := range targets {
+			if key := target.Env.TestRunner; key != "" && !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	keys = append(keys, env.CMAKE_PROGRAM.Key, env.NINJA_PROGRAM.Key)
+
+	for _, key := range keys {
+		if value := envConfig[key]; value != "" {
+			fmt.Fprintf(out, "%s=%s\n", key, value)
+		} else {
+			fmt.Fprintf(out, "%s is not set\n", key)
+		}
+	}
+	fmt.Fprintf(out, "MRS_SDK_QT_ROOT=%s\n", os.Getenv("MRS_SDK_QT_ROOT"))
+}
+
+// printBuildPlan prints the steps of every configuration with the job limit
+// the build would use.
+func printBuildPlan(out io.Writer, configs []BuildConfig, numOutdated int, opts Options) error {
+	concurrency, jobsPerTarget := jobBudget(opts.Jobs, opts.ParallelTargets, numOutdated)
+	fmt.Fprintf(out, "Up to %d target(s) at a time with %d job(s) each\n", concurrency, jobsPerTarget)
+
+	for _, config := range configs {
+		fmt.Fprintf(out, "\n%s:\n", config.label())
+		if config.UpToDate {
+			fmt.Fprintln(out, "  up to date, skipped (pass --force to rebuild)")
+			continue
+		}
+
+		reason, err := staleCacheReason(config)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			fmt.Fprintf(out, "  remove the CMake cache: %s\n", reason)
+		}
+		if config.EnvSetupScript != "" {
+			fmt.Fprintf(out, "  $ . %s\n", formatCommand([]string{config.EnvSetupScript}))
+		}
+		for _, entry := range config.Env {
+			fmt.Fprintf(out, "  $ export %s\n", formatCommand([]string{entry}))
+		}
+		for _, step := range config.plan(jobsPerTarget) {
+			fmt.Fprintf(out, "  $ %s\n", formatCommand(step.args))
+		}
+		if config.Artifact != "" {
+			fmt.Fprintf(out, "  verify the %s objects in %s\n", config.Target.Processor, config.Artifact)
+		}
+	}
+
+	return nil
+}
+
+// printInstallPlan prints the destination of InstallBuilds and every file it
+// would copy.
+func printInstallPlan(out io.Writer, sdkRoot string, targets []BuildTarget) error {
+	sdkInstallRoot, err := utils.ResolveSDKInstallRoot()
+	if err != nil {
+		return err
+	}
+	sdkVersion := utils.ResolveSDKVersion(sdkRoot)
+	sdkDevVersionRoot := filepath.Join(sdkInstallRoot, sdkVersion)
+	fmt.Fprintf(out, "Install SDK version %s in %s\n", sdkVersion, sdkDevVersionRoot)
+
+	for _, dir := range staticInstallDirs(sdkRoot, sdkDevVersionRoot) {
+		files, err := listDirectoryFiles(dir.src, dir.dst)
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %w", dir.name, err)
+		}
+		fmt.Fprintf(out, "\n%s (%d files):\n", dir.name, len(files))
+		printInstallFiles(out, files)
+	}
+
+	var libs []installFile
+	for _, target := range targets {
+		libs = append(libs, libraryInstallFile(target, sdkRoot, sdkDevVersionRoot))
+	}
+	fmt.Fprintf(out, "\nlibraries (%d files):\n", len(libs))
+	printInstallFiles(out, libs)
+
+	return nil
+}
+
+// printDemoSourcesPlan prints every file InstallDemoSources would copy.
+func printDemoSourcesPlan(out io.Writer, sdkRoot string) error {
+	sdkInstallRoot, err := utils.ResolveSDKInstallRoot()
+	if err != nil {
+		return err
+	}
+	demoInstallRoot := filepath.Join(sdkInstallRoot, utils.ResolveSDKVersion(sdkRoot), "demos")
+
+	files, err := listDirectoryFiles(filepath.Join(sdkRoot, "demos"), demoInstallRoot)
+	if err != nil {
+		return fmt.Errorf("failed to list demo sources: %w", err)
+	}
+	fmt.Fprintf(out, "Install demo sources in %s (%d files):\n", demoInstallRoot, len(files))
+	printInstallFiles(out, files)
+
+	return nil
+}
+
+func printInstallFiles(out io.Writer, files []installFile) {
+	for _, file := range files {
+		fmt.Fprintf(out, "  %s -> %s\n", file.Src, file.Dst)
+	}
+}
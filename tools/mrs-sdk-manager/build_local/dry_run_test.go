@@ -0,0 +1,90 @@
+package buildlocal
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPrintDryRunListsPlanWithoutSideEffects verifies that a dry run prints
+// the build commands, env values and installed files of every stage, and
+// neither creates build directories nor touches the SDK installation.
+func TestPrintDryRunListsPlanWithoutSideEffects(t *testing.T) {
+	sdkRoot, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatalf("failed to resolve repository root: %v", err)
+	}
+	installRoot := filepath.Join(t.TempDir(), "sdk")
+	t.Setenv("MRS_SDK_QT_ROOT", installRoot)
+
+	var targets []BuildTarget
+	for _, target := range testTargetMatrix(t).AllBuildTargets() {
+		if target.BuildDir() == "fusion-buildroot-qt5-debug" {
+			targets = append(targets, target)
+		}
+	}
+
+	var out bytes.Buffer
+	opts := Options{Install: true, Jobs: 8}
+	missing := []error{errors.New("ninja was not found")}
+	if err := printDryRun(&out, sdkRoot, testEnvConfig(), testBuildTools(), missing, BuildScopeAll, targets, opts); err != nil {
+		t.Fatalf("printDryRun returned error: %v", err)
+	}
+
+	libDir := filepath.Join(sdkRoot, "build", "fusion-buildroot-qt5-debug")
+	for _, expected := range []string{
+		"BUILDROOT_QT5_CXX_COMPILER=/tmp/buildroot/bin/arm-g++",
+		"NINJA_PROGRAM is not set",
+		"cmake 3.28.3: /usr/bin/cmake",
+		"ninja was not found",
+		"  $ /usr/bin/cmake -S " + filepath.Join(sdkRoot, "lib") + " -B " + libDir + " ",
+		"  $ /usr/bin/cmake --build " + libDir + " --target all --parallel 8",
+		filepath.Join(sdkRoot, "lib", "cmake", "mrs-sdk-qt", "config.cmake") + " -> " + installRoot,
+		filepath.Join(libDir, "artifacts", "libmrs-sdk-qt.a") + " -> " + installRoot,
+		"Install demo sources in " + installRoot,
+		"can-simulator/fusion-buildroot-qt5-debug:",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Fatalf("expected dry run output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+
+	assertFileMissing(t, installRoot)
+	assertFileMissing(t, filepath.Join(sdkRoot, "demos", "can-simulator", "mrs-sdk-qt"))
+}
+
+// TestLocateBuildToolsRunsNothing verifies that the tools of a dry run are
+// found without executing them, and that missing tools are reported instead
+// of failing.
+func TestLocateBuildToolsRunsNothing(t *testing.T) {
+	toolsDir := t.TempDir()
+	marker := filepath.Join(toolsDir, "executed")
+	for _, name := range []string{"cmake", "ninja", "g++"} {
+		writeTestFile(t, filepath.Join(toolsDir, name), "#!/bin/sh\ntouch "+marker+"\n")
+		if err := os.Chmod(filepath.Join(toolsDir, name), 0755); err != nil {
+			t.Fatalf("failed to make %s executable: %v", name, err)
+		}
+	}
+	t.Setenv("PATH", toolsDir)
+
+	envConfig := map[string]string{"DESKTOP_CXX_COMPILER": filepath.Join(toolsDir, "g++")}
+	tools, missing := locateBuildTools(envConfig, BuildScopeTest, testDesktopTargets())
+	if len(missing) != 0 {
+		t.Fatalf("expected every tool to be found, got %v", missing)
+	}
+	if tools.CMake.Path != filepath.Join(toolsDir, "cmake") || tools.CTest.Path != filepath.Join(toolsDir, "ctest") ||
+		tools.Generator != "Ninja" || tools.Make.Path != filepath.Join(toolsDir, "ninja") || len(tools.Compilers) != 1 {
+		t.Fatalf("unexpected tools: %+v", tools)
+	}
+	assertFileMissing(t, marker)
+
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	tools, missing = locateBuildTools(map[string]string{}, BuildScopeLibs, testDesktopTargets())
+	if len(missing) != 3 || tools.CMake.Path != "cmake" || tools.Make.Path != "ninja" {
+		t.Fatalf("expected cmake, the build program and the compiler to be reported missing, got %+v %v", tools, missing)
+	}
+}
@@ -0,0 +1,102 @@
+package buildlocal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// stampFileName is the file inside build/<target>/ that records the
+// fingerprint of the last successful build of that target.
+const stampFileName = "mrs-sdk-build.stamp"
+
+// hashTrackedSources hashes the path and content of every Git-tracked file
+// under lib/. This covers the library sources as well as the CMake toolchain
+// helpers, so it is shared by the fingerprints of all targets.
+func hashTrackedSources(sdkRoot string) (string, error) {
+	libRoot := filepath.Join(sdkRoot, "lib")
+	trackedFiles, _, err := trackedPathsForDirectory(libRoot)
+	if err != nil {
+		return "", err
+	}
+
+	paths := make([]string, 0, len(trackedFiles))
+	for path := range trackedFiles {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		fmt.Fprintf(h, "file %s\n", filepath.ToSlash(path))
+		f, err := os.Open(filepath.Join(libRoot, path))
+		if err != nil {
+			// A tracked file that was deleted in the working tree still has to
+			// change the fingerprint.
+			if errors.Is(err, fs.ErrNotExist) {
+				fmt.Fprintln(h, "missing")
+				continue
+			}
+			return "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// targetFingerprint combines the shared source digest with everything that is
+// specific to one target: the resolved env values it reads and its exact CMake
+// command lines.
+func targetFingerprint(sourceDigest string, config BuildConfig, envConfig map[string]string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "sources %s\n", sourceDigest)
+
+	var keys []string
+	for _, v := range requiredEnvVarsForTarget(config.Target) {
+		keys = append(keys, v.Key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		fmt.Fprintf(h, "env %s=%s\n", key, envConfig[key])
+	}
+
+	fmt.Fprintf(h, "configure %s\n", strings.Join(config.ConfigureCmd, "\x00"))
+	fmt.Fprintf(h, "build %s\n", strings.Join(config.BuildCmd, "\x00"))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isUpToDate reports whether the target's last successful build used the same
+// fingerprint and its library artifact is still present.
+func isUpToDate(sdkRoot string, config BuildConfig) bool {
+	buildDir := filepath.Join(sdkRoot, "build", config.Target.BuildDir())
+
+	stamp, err := os.ReadFile(filepath.Join(buildDir, stampFileName))
+	if err != nil {
+		return false
+	}
+	if strings.TrimSpace(string(stamp)) != config.Fingerprint {
+		return false
+	}
+
+	_, err = os.Stat(filepath.Join(buildDir, "artifacts", "libmrs-sdk-qt.a"))
+	return err == nil
+}
+
+// writeStamp records the fingerprint of a successful build.
+func writeStamp(sdkRoot string, config BuildConfig) error {
+	stampPath := filepath.Join(sdkRoot, "build", config.Target.BuildDir(), stampFileName)
+	return os.WriteFile(stampPath, []byte(config.Fingerprint+"\n"), 0644)
+}
@@ -0,0 +1,79 @@
+package buildlocal
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestTargetFingerprintTracksInputs verifies that editing a tracked library
+// source or changing a target's env value produces a new fingerprint, while
+// env keys used only by other targets leave it unchanged.
+func TestTargetFingerprintTracksInputs(t *testing.T) {
+	repoRoot := t.TempDir()
+	initTestRepo(t, repoRoot)
+	createFakeSDKRepo(t, repoRoot)
+
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{Targets: []string{"desktop-desktop-qt6"}})
+	if err != nil {
+		t.Fatalf("expected target selection to succeed, got error: %v", err)
+	}
+	envConfig := testEnvConfig()
+	config := getBuildConfigs(repoRoot, envConfig, testBuildTools(), targets)[0]
+
+	baseline := fingerprintFor(t, repoRoot, config, envConfig)
+
+	envConfig["YOCTO_QT5_SYSROOT"] = "/tmp/other/sysroot"
+	if fingerprintFor(t, repoRoot, config, envConfig) != baseline {
+		t.Fatal("expected unrelated env keys to leave the fingerprint unchanged")
+	}
+
+	envConfig["DESKTOP_QT6_PREFIX"] = "/opt/Qt/6.8.2"
+	changedEnv := fingerprintFor(t, repoRoot, config, envConfig)
+	if changedEnv == baseline {
+		t.Fatal("expected a changed Qt prefix to change the fingerprint")
+	}
+
+	writeTestFile(t, filepath.Join(repoRoot, "lib", "include", "mrs-sdk-qt", "sdk.hpp"), "edited header")
+	if fingerprintFor(t, repoRoot, config, envConfig) == changedEnv {
+		t.Fatal("expected an edited tracked source to change the fingerprint")
+	}
+}
+
+// TestIsUpToDateRequiresMatchingStampAndArtifact verifies that a target is
+// only skipped when its stamp matches and the library it produced still
+// exists.
+func TestIsUpToDateRequiresMatchingStampAndArtifact(t *testing.T) {
+	repoRoot := t.TempDir()
+	target := testTargetMatrix(t).AllBuildTargets()[0]
+	config := BuildConfig{Target: target, Fingerprint: "abc123"}
+	artifact := filepath.Join(repoRoot, "build", target.BuildDir(), "artifacts", "libmrs-sdk-qt.a")
+
+	if isUpToDate(repoRoot, config) {
+		t.Fatal("expected a target without a stamp to be out of date")
+	}
+
+	writeTestFile(t, artifact, "lib")
+	if err := writeStamp(repoRoot, config); err != nil {
+		t.Fatalf("writeStamp returned error: %v", err)
+	}
+	if !isUpToDate(repoRoot, config) {
+		t.Fatal("expected a target with a matching stamp to be up to date")
+	}
+
+	config.Fingerprint = "def456"
+	if isUpToDate(repoRoot, config) {
+		t.Fatal("expected a target with a different fingerprint to be out of date")
+	}
+}
+
+// fingerprintFor computes the full fingerprint of a configuration against the
+// current state of the fixture repository.
+func fingerprintFor(t *testing.T, repoRoot string, config BuildConfig, envConfig map[string]string) string {
+	t.Helper()
+
+	sourceDigest, err := hashTrackedSources(repoRoot)
+	if err != nil {
+		t.Fatalf("hashTrackedSources returned error: %v", err)
+	}
+	return targetFingerprint(sourceDigest, config, envConfig)
+}
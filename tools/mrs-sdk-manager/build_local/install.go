@@ -0,0 +1,171 @@
+package buildlocal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"mrs-sdk-manager/env"
+)
+
+// InstallOptions holds the command-line settings for an install invocation.
+type InstallOptions struct {
+	Version string   // SDK version to install as; empty for the version of the checkout
+	Targets []string // Build directories or target names; empty for every built target
+}
+
+// Install installs the libraries that build-local built earlier, without
+// building anything. It refuses artifacts that build-local would rebuild or
+// that were not built from the checked out commit, since they would be
+// installed under a version they do not belong to.
+func Install(sdkRoot string, opts InstallOptions) error {
+	matrix, err := LoadTargetMatrix(sdkRoot)
+	if err != nil {
+		return err
+	}
+
+	targets, err := builtTargets(sdkRoot, matrix, opts.Targets)
+	if err != nil {
+		return err
+	}
+
+	current := resolveBuildInfo(sdkRoot)
+	sdkVersion := opts.Version
+	if sdkVersion == "" {
+		sdkVersion = current.Version
+	} else if err := checkVersionName(sdkVersion); err != nil {
+		return err
+	}
+
+	envConfig, err := env.ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to read environment config: %w", err)
+	}
+	configs, _, err := planLibraryBuilds(sdkRoot, envConfig, fingerprintBuildTools(envConfig, targets), targets, false)
+	if err != nil {
+		return err
+	}
+	if err := checkArtifactsCurrent(configs, current); err != nil {
+		return err
+	}
+
+	return installBuildsAs(sdkRoot, sdkVersion, targets)
+}
+
+// fingerprintBuildTools returns the build tools that build-local would put
+// into the fingerprints of targets. The programs are only looked up, except
+// for ninja: build-local falls back to Unix Makefiles when ninja is too old,
+// which only its version check reveals.
+func fingerprintBuildTools(envConfig map[string]string, targets []BuildTarget) buildTools {
+	tools, _ := locateBuildTools(envConfig, BuildScopeLibs, targets)
+	if generator, makeTool, err := resolveGenerator(envConfig, qtToolsDirs(envConfig)); err == nil {
+		tools.Generator, tools.Make = generator, makeTool
+	}
+	return tools
+}
+
+// builtTargets returns the targets with a library artifact, optionally
+// narrowed to the named targets. Every named target must have been built.
+func builtTargets(sdkRoot string, matrix *TargetMatrix, targetArgs []string) ([]BuildTarget, error) {
+	isBuilt := func(target BuildTarget) bool {
+		_, err := os.Stat(libraryInstallFile(target, sdkRoot, "").Src)
+		return err == nil
+	}
+
+	if len(targetArgs) == 0 {
+		var targets []BuildTarget
+		for _, target := range matrix.AllBuildTargets() {
+			if isBuilt(target) {
+				targets = append(targets, target)
+			}
+		}
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("no built libraries found in %s; run 'mrs-sdk-manager build-local libs' first", filepath.Join(sdkRoot, "build"))
+		}
+		return targets, nil
+	}
+
+	var targets []BuildTarget
+	for _, targetArg := range targetArgs {
+		named, err := matrix.NamedBuildTargets([]string{targetArg})
+		if err != nil {
+			return nil, err
+		}
+		named = slices.DeleteFunc(named, func(target BuildTarget) bool { return !isBuilt(target) })
+		if len(named) == 0 {
+			return nil, fmt.Errorf("%s has not been built; run 'mrs-sdk-manager build-local libs --target %s' first", targetArg, targetArg)
+		}
+		for _, target := range named {
+			if !slices.Contains(targets, target) {
+				targets = append(targets, target)
+			}
+		}
+	}
+
+	return targets, nil
+}
+
+// checkArtifactsCurrent verifies that the library of every configuration was
+// built from the checked out commit without local changes, for the same
+// version, and that its fingerprint still matches the sources and build
+// settings. Modification times are not used, since checking out another
+// branch and back changes them without changing what build-local would build.
+// All problems are reported together.
+func checkArtifactsCurrent(configs []BuildConfig, current buildInfo) error {
+	var problems []string
+	for _, config := range configs {
+		target := config.Target
+		if _, err := os.Stat(config.Artifact); err != nil {
+			return fmt.Errorf("%s: cannot read the library: %w; run 'mrs-sdk-manager build-local libs --target %s' first", target.BuildDir(), err, target.Name())
+		}
+
+		info, err := readBuildInfo(config.BuildDir)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			problems = append(problems, fmt.Sprintf("%s: the build does not record its sources", target.BuildDir()))
+			continue
+		case err != nil:
+			return fmt.Errorf("%s: %w", target.BuildDir(), err)
+		}
+		if info.Commit != current.Commit {
+			problems = append(problems, fmt.Sprintf("%s: built from commit %s, but %s is checked out", target.BuildDir(), shortCommit(info.Commit), shortCommit(current.Commit)))
+		}
+		if info.Dirty {
+			problems = append(problems, fmt.Sprintf("%s: built with uncommitted changes in lib/", target.BuildDir()))
+		}
+		if info.Version != current.Version {
+			problems = append(problems, fmt.Sprintf("%s: built as version %s, but the checkout is version %s", target.BuildDir(), info.Version, current.Version))
+		}
+		if !config.UpToDate {
+			problems = append(problems, fmt.Sprintf("%s: the sources or build settings changed since the last build", target.BuildDir()))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("the existing builds do not match the checked out sources:\n  %s\nRebuild them with 'mrs-sdk-manager build-local libs --install'", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// checkVersionName rejects versions that would not name a directory directly
+// inside MRS_SDK_QT_ROOT.
+func checkVersionName(sdkVersion string) error {
+	if sdkVersion == "" || sdkVersion == "." || sdkVersion == ".." || strings.HasPrefix(sdkVersion, ".") || strings.ContainsAny(sdkVersion, `/\`) {
+		return fmt.Errorf("invalid version %q", sdkVersion)
+	}
+	return nil
+}
+
+func shortCommit(commit string) string {
+	if commit == "" {
+		return "no commit"
+	}
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
@@ -0,0 +1,146 @@
+package buildlocal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCheckArtifactsCurrentRequiresCheckedOutSources verifies that existing
+// artifacts are only accepted when build-local would not rebuild them and
+// they were built from the checked out commit and version without local
+// changes, regardless of file modification times.
+func TestCheckArtifactsCurrentRequiresCheckedOutSources(t *testing.T) {
+	repoRoot := t.TempDir()
+	t.Setenv("MRS_SDK_QT_ROOT", filepath.Join(t.TempDir(), "sdk"))
+	initTestRepo(t, repoRoot)
+	createFakeSDKRepo(t, repoRoot)
+	runGit(t, repoRoot, "commit", "-m", "Initial commit")
+	runGit(t, repoRoot, "tag", "v1.2.0")
+
+	targets, err := builtTargets(repoRoot, testTargetMatrix(t), []string{"desktop-desktop-qt6"})
+	if err != nil {
+		t.Fatalf("builtTargets returned error: %v", err)
+	}
+	if len(targets) != 3 {
+		t.Fatalf("expected every build type of the target, got %v", targets)
+	}
+
+	current := resolveBuildInfo(repoRoot)
+	if current.Version != "v1.2.0" || current.Commit == "" || current.Dirty {
+		t.Fatalf("unexpected build info for a clean checkout: %+v", current)
+	}
+	planConfigs := func() []BuildConfig {
+		configs, _, err := planLibraryBuilds(repoRoot, testEnvConfig(), testBuildTools(), targets, false)
+		if err != nil {
+			t.Fatalf("planLibraryBuilds returned error: %v", err)
+		}
+		return configs
+	}
+	for _, config := range planConfigs() {
+		if err := writeStamp(repoRoot, config); err != nil {
+			t.Fatalf("failed to write stamp: %v", err)
+		}
+		if err := writeBuildInfo(config.BuildDir, current); err != nil {
+			t.Fatalf("failed to write build info: %v", err)
+		}
+	}
+
+	// Checking out another branch and back only changes modification times.
+	header := filepath.Join(repoRoot, "lib", "include", "mrs-sdk-qt", "sdk.hpp")
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(header, future, future); err != nil {
+		t.Fatalf("failed to touch %s: %v", header, err)
+	}
+	if err := checkArtifactsCurrent(planConfigs(), current); err != nil {
+		t.Fatalf("expected fresh artifacts to be accepted, got error: %v", err)
+	}
+
+	stale := buildInfo{Version: "v1.1.0", Commit: "0123456789abcdef", Dirty: true}
+	if err := writeBuildInfo(filepath.Join(repoRoot, "build", targets[0].BuildDir()), stale); err != nil {
+		t.Fatalf("failed to write build info: %v", err)
+	}
+	if err := os.Remove(filepath.Join(repoRoot, "build", targets[1].BuildDir(), buildInfoFileName)); err != nil {
+		t.Fatalf("failed to remove build info: %v", err)
+	}
+	writeTestFile(t, header, "changed header")
+
+	err = checkArtifactsCurrent(planConfigs(), current)
+	if err == nil {
+		t.Fatal("expected stale artifacts to be refused")
+	}
+	for _, expected := range []string{
+		"desktop-desktop-qt6-debug: built from commit 0123456789ab, but " + current.Commit[:12] + " is checked out",
+		"desktop-desktop-qt6-debug: built with uncommitted changes in lib/",
+		"desktop-desktop-qt6-debug: built as version v1.1.0, but the checkout is version v1.2.0",
+		"desktop-desktop-qt6-debug: the sources or build settings changed since the last build",
+		"desktop-desktop-qt6-release: the build does not record its sources",
+		"desktop-desktop-qt6-relwithdebinfo: the sources or build settings changed since the last build",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected error to contain %q, got %v", expected, err)
+		}
+	}
+
+	if err := os.Remove(libraryInstallFile(targets[2], repoRoot, "").Src); err != nil {
+		t.Fatalf("failed to remove artifact: %v", err)
+	}
+	err = checkArtifactsCurrent(planConfigs(), current)
+	if err == nil || !strings.Contains(err.Error(), "desktop-desktop-qt6-relwithdebinfo: cannot read the library") || !strings.Contains(err.Error(), "build-local libs --target desktop-desktop-qt6") {
+		t.Fatalf("expected a missing artifact to name the target and build-local, got %v", err)
+	}
+}
+
+// TestBuiltTargetsRequiresArtifacts verifies that only targets with a library
+// artifact are installed, and that a named target without one is an error.
+func TestBuiltTargetsRequiresArtifacts(t *testing.T) {
+	repoRoot := t.TempDir()
+	matrix := testTargetMatrix(t)
+
+	if _, err := builtTargets(repoRoot, matrix, nil); err == nil {
+		t.Fatal("expected an error when nothing has been built")
+	}
+
+	writeTestFile(t, filepath.Join(repoRoot, "build", "mconn-yocto-qt5-release", "artifacts", "libmrs-sdk-qt.a"), "lib")
+	targets, err := builtTargets(repoRoot, matrix, nil)
+	if err != nil {
+		t.Fatalf("builtTargets returned error: %v", err)
+	}
+	if len(targets) != 1 || targets[0].BuildDir() != "mconn-yocto-qt5-release" {
+		t.Fatalf("expected only the built target, got %v", targets)
+	}
+
+	if _, err := builtTargets(repoRoot, matrix, []string{"mconn-yocto-qt5-debug"}); err == nil || !strings.Contains(err.Error(), "has not been built") {
+		t.Fatalf("expected an unbuilt target to be refused, got %v", err)
+	}
+}
+
+// TestFingerprintBuildToolsMatchesBuildLocal verifies that install expects the
+// generator build-local used, including its Unix Makefiles fallback for a
+// ninja that is too old.
+func TestFingerprintBuildToolsMatchesBuildLocal(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	t.Setenv("PATH", filepath.Join(root, "bin"))
+	writeTestTool(t, filepath.Join(root, "bin", "cmake"), "cmake version 3.28.1")
+	writeTestTool(t, filepath.Join(root, "bin", "ninja"), "1.2.0")
+	writeTestTool(t, filepath.Join(root, "bin", "make"), "GNU Make 4.3")
+	writeTestTool(t, filepath.Join(root, "g++"), "13")
+	envConfig := map[string]string{"DESKTOP_CXX_COMPILER": filepath.Join(root, "g++")}
+
+	built, err := resolveBuildTools(envConfig, BuildScopeLibs, testDesktopTargets())
+	if err != nil {
+		t.Fatalf("expected tools to resolve, got error: %v", err)
+	}
+	if built.Generator != "Unix Makefiles" {
+		t.Fatalf("expected build-local to fall back to Unix Makefiles, got %s", built.Generator)
+	}
+
+	expected := fingerprintBuildTools(envConfig, testDesktopTargets())
+	if expected.CMake.Path != built.CMake.Path || !slices.Equal(expected.generatorArgs(), built.generatorArgs()) {
+		t.Fatalf("expected install to use the tools of build-local %+v, got %+v", built, expected)
+	}
+}
@@ -0,0 +1,164 @@
+package buildlocal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"mrs-sdk-manager/env"
+
+	"gopkg.in/yaml.v3"
+)
+
+// targetMatrixPath is the build matrix file, relative to the SDK root.
+const targetMatrixPath = "lib/targets.yaml"
+
+// TargetMatrix is the set of targets the SDK library is built for, minus
+// build type. Targets keep the order of the matrix file.
+type TargetMatrix struct {
+	Targets []BuildTarget `yaml:"targets"`
+}
+
+var (
+	validQtVersions = []string{"qt5", "qt6"}
+	validOSes       = []string{"yocto", "buildroot", "desktop"}
+	identifierRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+	toolchainRegex  = regexp.MustCompile(`^[a-z0-9-]+$`)
+)
+
+// LoadTargetMatrix reads and validates the build matrix of the SDK at
+// sdkRoot.
+func LoadTargetMatrix(sdkRoot string) (*TargetMatrix, error) {
+	path := filepath.Join(sdkRoot, targetMatrixPath)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build matrix: %w", err)
+	}
+
+	var matrix TargetMatrix
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&matrix); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if err := matrix.validate(sdkRoot); err != nil {
+		return nil, fmt.Errorf("invalid build matrix %s: %w", path, err)
+	}
+
+	return &matrix, nil
+}
+
+// validate checks every entry of the matrix against the values the manager
+// and the toolchain helpers understand.
+func (m *TargetMatrix) validate(sdkRoot string) error {
+	if len(m.Targets) == 0 {
+		return fmt.Errorf("no targets defined")
+	}
+
+	var names []string
+	for i, target := range m.Targets {
+		if err := target.validate(sdkRoot); err != nil {
+			return fmt.Errorf("target %d (%s): %w", i+1, target.Name(), err)
+		}
+		if slices.Contains(names, target.Name()) {
+			return fmt.Errorf("target %d: duplicate target %s", i+1, target.Name())
+		}
+		names = append(names, target.Name())
+	}
+
+	return nil
+}
+
+// validate checks a single matrix entry.
+func (b *BuildTarget) validate(sdkRoot string) error {
+	if !slices.Contains(validQtVersions, b.QtVersion) {
+		return fmt.Errorf("invalid qt %q (expected one of: %s)", b.QtVersion, strings.Join(validQtVersions, ", "))
+	}
+	if !slices.Contains(validOSes, b.OS) {
+		return fmt.Errorf("invalid os %q (expected one of: %s)", b.OS, strings.Join(validOSes, ", "))
+	}
+	for _, field := range [][2]string{{"device", b.Device}, {"system", b.System}, {"processor", b.Processor}} {
+		if !identifierRegex.MatchString(field[1]) {
+			return fmt.Errorf("invalid %s %q (expected lowercase letters, digits and underscores)", field[0], field[1])
+		}
+	}
+	if !toolchainRegex.MatchString(b.Toolchain) {
+		return fmt.Errorf("invalid toolchain %q", b.Toolchain)
+	}
+	if major := strings.TrimPrefix(b.QtVersion, "qt"); !strings.HasPrefix(b.ExpectedQtVersion, major+".") {
+		return fmt.Errorf("expected_qt_version %q does not match qt %s", b.ExpectedQtVersion, b.QtVersion)
+	}
+	if b.OS == "yocto" && b.CompilerTarget == "" {
+		return fmt.Errorf("compiler_target is required for yocto targets")
+	}
+	if err := b.validateArch(); err != nil {
+		return err
+	}
+
+	if err := b.Env.validate(b.OS); err != nil {
+		return err
+	}
+
+	return b.validateToolchainHelpers(sdkRoot)
+}
+
+// validate checks that the keys required by the OS are set and that every
+// key is a known env config setting.
+func (e TargetEnv) validate(targetOS string) error {
+	roles := []struct {
+		name     string
+		key      string
+		required bool
+	}{
+		{"sysroot", e.Sysroot, targetOS != "desktop"},
+		{"cxx_compiler", e.CXXCompiler, true},
+		{"env_setup_script", e.EnvSetupScript, targetOS == "yocto"},
+		{"qt_prefix", e.QtPrefix, targetOS == "desktop"},
+		{"test_runner", e.TestRunner, false},
+	}
+	for _, role := range roles {
+		if role.key == "" {
+			if role.required {
+				return fmt.Errorf("env.%s is required for %s targets", role.name, targetOS)
+			}
+			continue
+		}
+		if _, ok := env.EnvVarsMetadataMap[role.key]; !ok {
+			return fmt.Errorf("env.%s: unknown env key %q", role.name, role.key)
+		}
+	}
+
+	return nil
+}
+
+// validateToolchainHelpers checks that the CMake and QMake toolchain helpers
+// exist and expect the same Qt version as the matrix for this device.
+func (b *BuildTarget) validateToolchainHelpers(sdkRoot string) error {
+	versionRegex := regexp.MustCompile(`MRS_SDK_QT_EXPECTED_QT_VERSION_` + strings.ToUpper(b.Device) + `\b[\s="]*([0-9.]+)`)
+	helpers := []string{
+		filepath.Join("lib/cmake/mrs-sdk-qt/toolchains", b.Toolchain+".cmake"),
+		filepath.Join("lib/qmake/mrs-sdk-qt/toolchains", b.Toolchain+".pri"),
+	}
+	for _, helper := range helpers {
+		data, err := os.ReadFile(filepath.Join(sdkRoot, helper))
+		if err != nil {
+			return fmt.Errorf("toolchain helper %s not found", helper)
+		}
+		match := versionRegex.FindSubmatch(data)
+		if match == nil {
+			return fmt.Errorf("%s does not define an expected Qt version for %s", helper, b.Device)
+		}
+		if string(match[1]) != b.ExpectedQtVersion {
+			return fmt.Errorf("expected_qt_version %s does not match %s in %s", b.ExpectedQtVersion, match[1], helper)
+		}
+	}
+
+	return nil
+}
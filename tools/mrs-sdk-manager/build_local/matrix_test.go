@@ -0,0 +1,128 @@
+package buildlocal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestLoadTargetMatrixReadsRepoMatrix verifies that the matrix shipped in the
+// repository is valid and keeps the historical target order, which the
+// installation and progress output rely on.
+func TestLoadTargetMatrixReadsRepoMatrix(t *testing.T) {
+	matrix := testTargetMatrix(t)
+
+	var names []string
+	for _, target := range matrix.Targets {
+		names = append(names, target.Name())
+	}
+	expected := []string{
+		"mconn-yocto-qt5",
+		"mconn-buildroot-qt5",
+		"fusion-buildroot-qt5",
+		"desktop-desktop-qt5",
+		"desktop-desktop-qt6",
+		"neuralplex-yocto-qt6",
+	}
+	if !slices.Equal(names, expected) {
+		t.Fatalf("expected targets %v, got %v", expected, names)
+	}
+}
+
+// TestLoadTargetMatrixRejectsInvalidEntries verifies that mistakes in the
+// matrix file are reported before any build starts instead of surfacing as
+// CMake errors later.
+func TestLoadTargetMatrixRejectsInvalidEntries(t *testing.T) {
+	valid := `targets:
+  - device: desktop
+    os: desktop
+    qt: qt6
+    system: linux
+    processor: x86_64
+    toolchain: desktop-qt6
+    expected_qt_version: "6.8"
+    env:
+      cxx_compiler: DESKTOP_CXX_COMPILER
+      qt_prefix: DESKTOP_QT6_PREFIX
+`
+	testCases := []struct {
+		name     string
+		matrix   string
+		expected string
+	}{
+		{"unknown field", strings.Replace(valid, "system:", "platform:", 1), "field platform not found"},
+		{"unknown os", strings.Replace(valid, "os: desktop", "os: windows", 1), `invalid os "windows"`},
+		{"unknown env key", strings.Replace(valid, "DESKTOP_QT6_PREFIX", "DESKTOP_QT7_PREFIX", 1), `unknown env key "DESKTOP_QT7_PREFIX"`},
+		{"missing env key", strings.Replace(valid, "      qt_prefix: DESKTOP_QT6_PREFIX\n", "", 1), "env.qt_prefix is required"},
+		{"wrong qt major", strings.Replace(valid, `"6.8"`, `"5.15"`, 1), "does not match qt qt6"},
+		{"helper mismatch", strings.Replace(valid, `"6.8"`, `"6.9"`, 1), "does not match 6.8"},
+		{"missing helper", strings.Replace(valid, "toolchain: desktop-qt6", "toolchain: windows-qt6", 1), "windows-qt6.cmake not found"},
+		{"duplicate", valid + strings.TrimPrefix(valid, "targets:\n"), "duplicate target desktop-desktop-qt6"},
+		{"empty", "", "no targets defined"},
+	}
+
+	sdkRoot := t.TempDir()
+	writeTestFile(t, filepath.Join(sdkRoot, "lib/cmake/mrs-sdk-qt/toolchains/desktop-qt6.cmake"),
+		`set(MRS_SDK_QT_EXPECTED_QT_VERSION_DESKTOP "6.8" CACHE STRING "Expected desktop Qt version" FORCE)`+"\n")
+	writeTestFile(t, filepath.Join(sdkRoot, "lib/qmake/mrs-sdk-qt/toolchains/desktop-qt6.pri"),
+		"MRS_SDK_QT_EXPECTED_QT_VERSION_DESKTOP = 6.8\n")
+
+	writeTestFile(t, filepath.Join(sdkRoot, targetMatrixPath), valid)
+	if _, err := LoadTargetMatrix(sdkRoot); err != nil {
+		t.Fatalf("expected the baseline matrix to load, got error: %v", err)
+	}
+
+	for _, testCase := range testCases {
+		writeTestFile(t, filepath.Join(sdkRoot, targetMatrixPath), testCase.matrix)
+		_, err := LoadTargetMatrix(sdkRoot)
+		if err == nil || !strings.Contains(err.Error(), testCase.expected) {
+			t.Fatalf("%s: expected error containing %q, got %v", testCase.name, testCase.expected, err)
+		}
+	}
+}
+
+// TestRequiredEnvVarsForTargetFollowsMatrix verifies that the env keys a
+// target needs come from its matrix entry, so a new target with its own SDK
+// does not require code changes.
+func TestRequiredEnvVarsForTargetFollowsMatrix(t *testing.T) {
+	target := BuildTarget{
+		OS: "buildroot",
+		Env: TargetEnv{
+			Sysroot:     "YOCTO_QT6_SYSROOT",
+			CXXCompiler: "DESKTOP_CXX_COMPILER",
+			TestRunner:  "AARCH64_TEST_RUNNER",
+		},
+	}
+
+	var keys []string
+	for _, v := range requiredEnvVarsForTarget(target) {
+		keys = append(keys, v.Key)
+	}
+
+	// The test runner is optional and must not be required.
+	expected := []string{"YOCTO_QT6_SYSROOT", "DESKTOP_CXX_COMPILER"}
+	if !slices.Equal(keys, expected) {
+		t.Fatalf("expected required keys %v, got %v", expected, keys)
+	}
+}
+
+// testTargetMatrix loads the build matrix of this repository.
+func testTargetMatrix(t *testing.T) *TargetMatrix {
+	t.Helper()
+
+	sdkRoot, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatalf("failed to resolve repository root: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(sdkRoot, targetMatrixPath)); err != nil {
+		t.Fatalf("expected %s in the repository root: %v", targetMatrixPath, err)
+	}
+
+	matrix, err := LoadTargetMatrix(sdkRoot)
+	if err != nil {
+		t.Fatalf("failed to load build matrix: %v", err)
+	}
+	return matrix
+}
@@ -0,0 +1,30 @@
+package buildlocal
+
+import "runtime"
+
+// defaultJobsPerTarget is the number of compile jobs each target gets when
+// --parallel-targets is not given. It keeps a handful of cores per Ninja
+// process instead of running every target at once on one core each.
+const defaultJobsPerTarget = 4
+
+// jobBudget splits a total CPU budget across the targets that actually need
+// building. It returns how many targets may build concurrently and how many
+// jobs each `cmake --build` invocation may use, so that concurrency*perTarget
+// never exceeds the total. Zero or negative inputs select the defaults, which
+// are derived from runtime.NumCPU().
+func jobBudget(jobs, parallelTargets, numTargets int) (concurrency, perTarget int) {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+
+	concurrency = parallelTargets
+	if concurrency <= 0 {
+		concurrency = jobs / defaultJobsPerTarget
+	}
+	concurrency = min(concurrency, numTargets, jobs)
+	concurrency = max(concurrency, 1)
+
+	perTarget = max(jobs/concurrency, 1)
+
+	return concurrency, perTarget
+}
@@ -0,0 +1,45 @@
+package buildlocal
+
+import (
+	"runtime"
+	"testing"
+)
+
+// TestJobBudgetStaysWithinTotal verifies that the concurrent cmake --build
+// invocations never use more jobs than the total budget, whatever mix of
+// flags is passed.
+func TestJobBudgetStaysWithinTotal(t *testing.T) {
+	testCases := []struct {
+		jobs, parallelTargets, numTargets int
+		concurrency, perTarget            int
+	}{
+		{jobs: 8, parallelTargets: 0, numTargets: 6, concurrency: 2, perTarget: 4},
+		{jobs: 64, parallelTargets: 0, numTargets: 6, concurrency: 6, perTarget: 10},
+		{jobs: 64, parallelTargets: 0, numTargets: 18, concurrency: 16, perTarget: 4},
+		{jobs: 8, parallelTargets: 3, numTargets: 6, concurrency: 3, perTarget: 2},
+		{jobs: 2, parallelTargets: 4, numTargets: 6, concurrency: 2, perTarget: 1},
+		{jobs: 3, parallelTargets: 0, numTargets: 6, concurrency: 1, perTarget: 3},
+		{jobs: 8, parallelTargets: 0, numTargets: 0, concurrency: 1, perTarget: 8},
+	}
+
+	for _, testCase := range testCases {
+		concurrency, perTarget := jobBudget(testCase.jobs, testCase.parallelTargets, testCase.numTargets)
+		if concurrency != testCase.concurrency || perTarget != testCase.perTarget {
+			t.Fatalf("jobBudget(%d, %d, %d) = (%d, %d), expected (%d, %d)",
+				testCase.jobs, testCase.parallelTargets, testCase.numTargets,
+				concurrency, perTarget, testCase.concurrency, testCase.perTarget)
+		}
+		if concurrency*perTarget > testCase.jobs {
+			t.Fatalf("jobBudget(%d, %d, %d) exceeds the total budget", testCase.jobs, testCase.parallelTargets, testCase.numTargets)
+		}
+	}
+}
+
+// TestJobBudgetDefaultsToCPUCount verifies that omitting --jobs uses the
+// host's CPU count as the total budget.
+func TestJobBudgetDefaultsToCPUCount(t *testing.T) {
+	concurrency, perTarget := jobBudget(0, 1, 5)
+	if concurrency != 1 || perTarget != runtime.NumCPU() {
+		t.Fatalf("expected (1, %d), got (%d, %d)", runtime.NumCPU(), concurrency, perTarget)
+	}
+}
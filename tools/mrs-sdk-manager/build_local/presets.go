@@ -0,0 +1,201 @@
+package buildlocal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mrs-sdk-manager/env"
+	"mrs-sdk-manager/utils"
+
+	"github.com/fatih/color"
+)
+
+const (
+	presetsFileName     = "CMakePresets.json"
+	userPresetsFileName = "CMakeUserPresets.json"
+	// Version 3 is the first that supports toolchainFile. It needs CMake 3.21.
+	presetsVersion = 3
+)
+
+// cmakePresets is the subset of the CMake presets file format written by
+// `mrs-sdk-manager presets`.
+type cmakePresets struct {
+	Version              int               `json:"version"`
+	CMakeMinimumRequired *cmakeVersion     `json:"cmakeMinimumRequired,omitempty"`
+	ConfigurePresets     []configurePreset `json:"configurePresets"`
+	BuildPresets         []buildPreset     `json:"buildPresets,omitempty"`
+}
+
+type cmakeVersion struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+	Patch int `json:"patch"`
+}
+
+type configurePreset struct {
+	Name           string                   `json:"name"`
+	DisplayName    string                   `json:"displayName,omitempty"`
+	Hidden         bool                     `json:"hidden,omitempty"`
+	Inherits       []string                 `json:"inherits,omitempty"`
+	Generator      string                   `json:"generator,omitempty"`
+	BinaryDir      string                   `json:"binaryDir,omitempty"`
+	ToolchainFile  string                   `json:"toolchainFile,omitempty"`
+	CacheVariables map[string]cacheVariable `json:"cacheVariables,omitempty"`
+}
+
+type cacheVariable struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type buildPreset struct {
+	Name            string `json:"name"`
+	ConfigurePreset string `json:"configurePreset"`
+}
+
+// WritePresets writes lib/CMakePresets.json and lib/CMakeUserPresets.json
+// with a configure and build preset for every target and build type, so the
+// SDK library can be configured from an IDE exactly like build-local does.
+// Paths from the env config only go into the user presets file, which keeps
+// the shared file free of machine-specific settings.
+func WritePresets(sdkRoot string) error {
+	matrix, err := LoadTargetMatrix(sdkRoot)
+	if err != nil {
+		return err
+	}
+
+	envConfig, err := env.ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to read environment config: %w", err)
+	}
+
+	utils.PrintTaskStart("Writing CMake presets...")
+
+	generator, makeProgram, err := resolveGenerator(envConfig, qtToolsDirs(envConfig))
+	if err != nil {
+		color.Yellow("No generator selected: %v", err)
+	}
+
+	shared, user, skipped := generatePresets(matrix, envConfig, generator, makeProgram.Path)
+	for _, file := range []struct {
+		name    string
+		presets cmakePresets
+	}{
+		{presetsFileName, shared},
+		{userPresetsFileName, user},
+	} {
+		path := filepath.Join(sdkRoot, "lib", file.name)
+		if err := writePresetsFile(path, file.presets); err != nil {
+			return err
+		}
+		color.White("Wrote %s", path)
+	}
+
+	for _, message := range skipped {
+		color.Yellow("Skipped %s", message)
+	}
+
+	utils.PrintSuccess(fmt.Sprintf("Wrote %d configure presets", len(user.ConfigurePresets)))
+	return nil
+}
+
+// generatePresets returns the shared and user presets for the matrix. The
+// shared file has a hidden base preset per target and per build type. The
+// user file combines them into one visible preset per target and build type
+// and adds the kit settings from the env config. Targets with missing env
+// config keys are skipped and described in the returned messages.
+func generatePresets(matrix *TargetMatrix, envConfig map[string]string, generator, makeProgram string) (cmakePresets, cmakePresets, []string) {
+	shared := cmakePresets{
+		Version:              presetsVersion,
+		CMakeMinimumRequired: &cmakeVersion{Major: 3, Minor: 21},
+	}
+	user := cmakePresets{Version: presetsVersion}
+
+	for _, target := range matrix.Targets {
+		shared.ConfigurePresets = append(shared.ConfigurePresets, configurePreset{
+			Name:   target.Name(),
+			Hidden: true,
+			// Keep preset builds apart from the build-local build directories
+			// so that their stamps stay valid.
+			BinaryDir:     "${sourceDir}/../build/presets/${presetName}",
+			ToolchainFile: "${sourceDir}/cmake/mrs-sdk-qt/toolchains/" + target.Toolchain + ".cmake",
+			CacheVariables: map[string]cacheVariable{
+				"MRS_SDK_QT_TARGET_DEVICE":      {Type: "STRING", Value: target.Device},
+				"CMAKE_EXPORT_COMPILE_COMMANDS": {Type: "BOOL", Value: "ON"},
+			},
+		})
+	}
+	for _, buildType := range validBuildTypes {
+		target := BuildTarget{BuildType: buildType}
+		shared.ConfigurePresets = append(shared.ConfigurePresets, configurePreset{
+			Name:   buildType,
+			Hidden: true,
+			CacheVariables: map[string]cacheVariable{
+				"CMAKE_BUILD_TYPE": {Type: "STRING", Value: target.CMakeBuildType()},
+			},
+		})
+	}
+
+	var skipped []string
+	for _, target := range matrix.Targets {
+		var missingKeys []string
+		for _, v := range requiredEnvVarsForTarget(target) {
+			if envConfig[v.Key] == "" {
+				missingKeys = append(missingKeys, v.Key)
+			}
+		}
+		if len(missingKeys) > 0 {
+			skipped = append(skipped, fmt.Sprintf("%s (missing env config: %s)", target.Name(), strings.Join(missingKeys, ", ")))
+			continue
+		}
+
+		for _, buildType := range validBuildTypes {
+			target.BuildType = buildType
+			preset := configurePreset{
+				Name:           target.BuildDir(),
+				DisplayName:    fmt.Sprintf("%s (%s)", target.Name(), target.CMakeBuildType()),
+				Inherits:       []string{target.Name(), buildType},
+				Generator:      generator,
+				CacheVariables: map[string]cacheVariable{},
+			}
+			args := cmakeKitArgs(envConfig, target)
+			if makeProgram != "" {
+				args = append(args, "-DCMAKE_MAKE_PROGRAM:FILEPATH="+makeProgram)
+			}
+			if sdkInstallRoot := os.Getenv("MRS_SDK_QT_ROOT"); sdkInstallRoot != "" {
+				args = append(args, "-DMRS_SDK_QT_ROOT:STRING="+sdkInstallRoot)
+			}
+			for _, arg := range args {
+				name, variable := parseCacheArg(arg)
+				preset.CacheVariables[name] = variable
+			}
+
+			user.ConfigurePresets = append(user.ConfigurePresets, preset)
+			user.BuildPresets = append(user.BuildPresets, buildPreset{Name: preset.Name, ConfigurePreset: preset.Name})
+		}
+	}
+
+	return shared, user, skipped
+}
+
+// parseCacheArg splits a "-DNAME:TYPE=value" argument into a preset cache
+// variable.
+func parseCacheArg(arg string) (string, cacheVariable) {
+	definition, value, _ := strings.Cut(strings.TrimPrefix(arg, "-D"), "=")
+	name, varType, _ := strings.Cut(definition, ":")
+	return name, cacheVariable{Type: varType, Value: value}
+}
+
+func writePresetsFile(path string, presets cmakePresets) error {
+	data, err := json.MarshalIndent(presets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
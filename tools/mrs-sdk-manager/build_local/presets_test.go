@@ -0,0 +1,91 @@
+package buildlocal
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestGeneratePresetsKeepsUserPathsOutOfSharedFile verifies that every target
+// and build type gets a visible preset that inherits from the shared file,
+// and that paths from the env config only appear in the user presets.
+func TestGeneratePresetsKeepsUserPathsOutOfSharedFile(t *testing.T) {
+	t.Setenv("MRS_SDK_QT_ROOT", "/tmp/mrs-sdk-root")
+	matrix := testTargetMatrix(t)
+
+	shared, user, skipped := generatePresets(matrix, testEnvConfig(), "Ninja", "/usr/bin/ninja")
+	if len(skipped) != 0 {
+		t.Fatalf("expected no skipped targets, got %v", skipped)
+	}
+
+	data, err := json.Marshal(shared)
+	if err != nil {
+		t.Fatalf("failed to encode shared presets: %v", err)
+	}
+	for _, value := range append(slices.Collect(maps.Values(testEnvConfig())), "/tmp/mrs-sdk-root", "/usr/bin/ninja") {
+		if strings.Contains(string(data), value) {
+			t.Fatalf("expected shared presets to contain no user paths, found %q in %s", value, data)
+		}
+	}
+
+	var sharedNames []string
+	for _, preset := range shared.ConfigurePresets {
+		if !preset.Hidden {
+			t.Fatalf("expected shared preset %s to be hidden", preset.Name)
+		}
+		sharedNames = append(sharedNames, preset.Name)
+	}
+
+	if expected := len(matrix.AllBuildTargets()); len(user.ConfigurePresets) != expected || len(user.BuildPresets) != expected {
+		t.Fatalf("expected %d configure and build presets, got %d and %d", expected, len(user.ConfigurePresets), len(user.BuildPresets))
+	}
+	for _, preset := range user.ConfigurePresets {
+		for _, parent := range preset.Inherits {
+			if !slices.Contains(sharedNames, parent) {
+				t.Fatalf("expected %s to inherit from shared presets, got %v", preset.Name, preset.Inherits)
+			}
+		}
+	}
+
+	yocto := user.ConfigurePresets[0]
+	if yocto.Name != "mconn-yocto-qt5-debug" || !slices.Equal(yocto.Inherits, []string{"mconn-yocto-qt5", "debug"}) {
+		t.Fatalf("unexpected first preset %+v", yocto)
+	}
+	expectedVars := map[string]cacheVariable{
+		"YOCTO_QT5_ENV_SETUP_SCRIPT": {Type: "FILEPATH", Value: "/tmp/yocto/environment-setup"},
+		"CMAKE_SYSROOT":              {Type: "PATH", Value: "/tmp/yocto/sysroot"},
+		"CMAKE_MAKE_PROGRAM":         {Type: "FILEPATH", Value: "/usr/bin/ninja"},
+		"MRS_SDK_QT_ROOT":            {Type: "STRING", Value: "/tmp/mrs-sdk-root"},
+	}
+	for name, expected := range expectedVars {
+		if yocto.CacheVariables[name] != expected {
+			t.Fatalf("expected cache variable %s=%+v, got %+v", name, expected, yocto.CacheVariables[name])
+		}
+	}
+}
+
+// TestGeneratePresetsSkipsTargetsWithoutEnv verifies that targets whose kit
+// is not configured are left out instead of producing presets that cannot
+// configure.
+func TestGeneratePresetsSkipsTargetsWithoutEnv(t *testing.T) {
+	envConfig := map[string]string{
+		"DESKTOP_CXX_COMPILER": "/usr/bin/g++",
+		"DESKTOP_QT6_PREFIX":   "/opt/Qt/6",
+	}
+
+	_, user, skipped := generatePresets(testTargetMatrix(t), envConfig, "", "")
+
+	var names []string
+	for _, preset := range user.ConfigurePresets {
+		names = append(names, preset.Name)
+	}
+	expected := []string{"desktop-desktop-qt6-debug", "desktop-desktop-qt6-release", "desktop-desktop-qt6-relwithdebinfo"}
+	if !slices.Equal(names, expected) {
+		t.Fatalf("expected presets %v, got %v", expected, names)
+	}
+	if len(skipped) != 5 || !strings.Contains(skipped[0], "mconn-yocto-qt5 (missing env config: YOCTO_QT5_SYSROOT") {
+		t.Fatalf("expected the other targets to be skipped with their missing keys, got %v", skipped)
+	}
+}
@@ -0,0 +1,71 @@
+package buildlocal
+
+import (
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// killGracePeriod is how long a cancelled build may take to exit after
+// SIGTERM before its whole process group is killed.
+const killGracePeriod = 5 * time.Second
+
+// processGroup is the process group of a command started with
+// configureProcessGroup.
+type processGroup struct {
+	mu        sync.Mutex
+	pgid      int
+	killTimer *time.Timer // Pending SIGKILL after a cancellation; nil before
+	waited    bool        // The command has been waited on, so pgid may be reused
+}
+
+// configureProcessGroup starts cmd in its own process group and makes context
+// cancellation terminate the whole group. Without this, only the top-level
+// process would be signalled and the cmake, ninja and compiler processes it
+// spawned would keep running. Running in a separate group also keeps Ctrl-C
+// in the terminal from reaching the children directly, so that build-local
+// decides how they are stopped. The caller must call finished on the returned
+// group once cmd has been waited on.
+func configureProcessGroup(cmd *exec.Cmd) *processGroup {
+	group := &processGroup{}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return group.terminate(cmd.Process.Pid)
+	}
+	// Stop waiting for output from stragglers once the group has been killed.
+	cmd.WaitDelay = killGracePeriod + time.Second
+	return group
+}
+
+// terminate sends SIGTERM to the group and SIGKILL after killGracePeriod,
+// unless the command has been waited on by then.
+func (g *processGroup) terminate(pid int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.pgid = -pid
+	if err := syscall.Kill(g.pgid, syscall.SIGTERM); err != nil {
+		return err
+	}
+	g.killTimer = time.AfterFunc(killGracePeriod, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if !g.waited {
+			_ = syscall.Kill(g.pgid, syscall.SIGKILL)
+		}
+	})
+	return nil
+}
+
+// finished records that the command has been waited on. A pending SIGKILL is
+// dropped, since the group ID may already belong to other processes.
+func (g *processGroup) finished() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.waited = true
+	if g.killTimer != nil {
+		g.killTimer.Stop()
+	}
+}
@@ -0,0 +1,34 @@
+package buildlocal
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// TestProcessGroupDropsKillAfterWait verifies that a cancelled command is
+// terminated, and that the delayed SIGKILL is not sent to its process group
+// once the command has been waited on.
+func TestProcessGroupDropsKillAfterWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	cmd := exec.CommandContext(ctx, "sleep", "30")
+	group := configureProcessGroup(cmd)
+	start := time.Now()
+	err := cmd.Run()
+	group.finished()
+	if err == nil || time.Since(start) > killGracePeriod {
+		t.Fatalf("expected SIGTERM to stop the command promptly, got %v after %s", err, time.Since(start))
+	}
+
+	group.mu.Lock()
+	defer group.mu.Unlock()
+	if group.killTimer == nil {
+		t.Fatal("expected a SIGKILL to have been scheduled by the cancellation")
+	}
+	if group.killTimer.Stop() {
+		t.Fatal("expected the pending SIGKILL to be dropped once the command was waited on")
+	}
+}
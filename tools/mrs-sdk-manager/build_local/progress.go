@@ -0,0 +1,297 @@
+package buildlocal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+)
+
+// OutputMode selects how build-local reports the progress of each target.
+type OutputMode string
+
+const (
+	OutputAuto  OutputMode = "auto"  // table on a terminal, plain otherwise
+	OutputTable OutputMode = "table" // status table redrawn in place with ANSI codes
+	OutputPlain OutputMode = "plain" // one line per state change, for CI logs
+	OutputJSON  OutputMode = "json"  // one JSON object per state change
+)
+
+// ParseOutputMode validates the value passed to `build-local --output`. An
+// empty value selects OutputAuto.
+func ParseOutputMode(raw string) (OutputMode, error) {
+	if raw == "" {
+		return OutputAuto, nil
+	}
+
+	mode := OutputMode(raw)
+	switch mode {
+	case OutputAuto, OutputTable, OutputPlain, OutputJSON:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid output mode %q (expected one of: auto, table, plain, json)", raw)
+	}
+}
+
+// resolve turns OutputAuto into a concrete mode for the given output file.
+func (mode OutputMode) resolve(out *os.File) OutputMode {
+	if mode != OutputAuto {
+		return mode
+	}
+	if isatty.IsTerminal(out.Fd()) && os.Getenv("TERM") != "dumb" {
+		return OutputTable
+	}
+	return OutputPlain
+}
+
+// buildState is the lifecycle state of a single target in a build-local run.
+type buildState string
+
+const (
+	statePending   buildState = "pending"
+	stateBuilding  buildState = "building"
+	stateSuccess   buildState = "success"
+	stateFailed    buildState = "failed"
+	stateUpToDate  buildState = "up-to-date"
+	stateCancelled buildState = "cancelled"
+)
+
+// progressReporter receives every state change of the targets in a run.
+// Implementations must be safe for concurrent use.
+type progressReporter interface {
+	// begin reports the initial state of every configuration.
+	begin(configs []BuildConfig)
+	// update reports a state change of configs[i]. err is only set for
+	// stateFailed.
+	update(i int, state buildState, err error)
+	// finish is called once after the last update, including when the run
+	// was interrupted.
+	finish()
+}
+
+// newProgressReporter creates the reporter for a concrete output mode.
+func newProgressReporter(mode OutputMode, out io.Writer) progressReporter {
+	switch mode {
+	case OutputJSON:
+		return &jsonReporter{out: out}
+	case OutputPlain:
+		return &plainReporter{out: out}
+	default:
+		return &tableReporter{out: out}
+	}
+}
+
+// progressTimer tracks when each target started building so reporters can
+// print durations.
+type progressTimer struct {
+	mu      sync.Mutex
+	configs []BuildConfig
+	started []time.Time
+}
+
+func (p *progressTimer) reset(configs []BuildConfig) {
+	p.configs = configs
+	p.started = make([]time.Time, len(configs))
+}
+
+// elapsed records the start time for stateBuilding and returns the time spent
+// building for the other states.
+func (p *progressTimer) elapsed(i int, state buildState) time.Duration {
+	if state == stateBuilding {
+		p.started[i] = time.Now()
+		return 0
+	}
+	if p.started[i].IsZero() {
+		return 0
+	}
+	return time.Since(p.started[i])
+}
+
+// tableReporter redraws a status table in place. It is only suitable for
+// interactive terminals.
+type tableReporter struct {
+	progressTimer
+	out        io.Writer
+	statusMsgs []string
+	maxMsgLen  int
+}
+
+func (r *tableReporter) begin(configs []BuildConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reset(configs)
+
+	// Pre-calculate all status messages and find max length for column alignment
+	r.statusMsgs = make([]string, len(configs))
+	for i, config := range configs {
+		r.statusMsgs[i] = fmt.Sprintf("[%d/%d] Building %s for %s", i+1, len(configs), config.subject(), config.Target.BuildDir())
+		r.maxMsgLen = max(r.maxMsgLen, len(r.statusMsgs[i]))
+	}
+
+	// Hide the cursor while the table is redrawn; finish shows it again.
+	fmt.Fprint(r.out, "\033[?25l")
+
+	// Print initial status lines with padding
+	for i, config := range configs {
+		status := color.YellowString("Pending")
+		if config.UpToDate {
+			status = color.GreenString("✓ Up to date")
+		}
+		fmt.Fprintln(r.out, r.line(i, status))
+	}
+}
+
+func (r *tableReporter) update(i int, state buildState, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.elapsed(i, state)
+
+	var status string
+	switch state {
+	case stateBuilding:
+		status = color.CyanString("Building...")
+	case stateSuccess:
+		status = color.GreenString("✓ Success")
+	case stateFailed:
+		status = color.RedString("✗ Failed")
+	case stateUpToDate:
+		status = color.GreenString("✓ Up to date")
+	case stateCancelled:
+		status = color.YellowString("- Cancelled")
+	default:
+		status = color.YellowString("Pending")
+	}
+
+	// Figure out how many lines to move.
+	// The cursor is always at the bottom to start.
+	linesToMove := len(r.configs) - i
+	// Move up to the line for this build using ANSI code.
+	fmt.Fprintf(r.out, "\033[%dA", linesToMove)
+	// Clear the line using ANSI code.
+	fmt.Fprint(r.out, "\r"+"\033[K")
+	// Print the new status line.
+	fmt.Fprintln(r.out, r.line(i, status))
+	// Move the cursor back down to the bottom using ANSI code.
+	fmt.Fprintf(r.out, "\033[%dB", linesToMove)
+}
+
+func (r *tableReporter) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Every update leaves the cursor below the table, so only its visibility
+	// needs restoring.
+	fmt.Fprint(r.out, "\033[?25h")
+}
+
+func (r *tableReporter) line(i int, status string) string {
+	padding := strings.Repeat(" ", r.maxMsgLen-len(r.statusMsgs[i])+3)
+	return color.WhiteString(r.statusMsgs[i]) + padding + " " + status
+}
+
+// plainReporter prints one line per state change, e.g.
+// "[3/5] fusion-buildroot-qt5-debug: success in 42s".
+type plainReporter struct {
+	progressTimer
+	out io.Writer
+}
+
+func (r *plainReporter) begin(configs []BuildConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reset(configs)
+	for i, config := range configs {
+		if config.UpToDate {
+			r.print(i, stateUpToDate, 0)
+		}
+	}
+}
+
+func (r *plainReporter) update(i int, state buildState, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.print(i, state, r.elapsed(i, state))
+}
+
+func (r *plainReporter) finish() {}
+
+func (r *plainReporter) print(i int, state buildState, elapsed time.Duration) {
+	line := fmt.Sprintf("[%d/%d] %s: %s", i+1, len(r.configs), r.configs[i].label(), state)
+	if elapsed > 0 {
+		line += " in " + elapsed.Round(time.Second).String()
+	}
+	fmt.Fprintln(r.out, line)
+}
+
+// buildEvent is the JSON object emitted for each state change in
+// `--output json` mode.
+type buildEvent struct {
+	Time     string     `json:"time"`
+	Target   string     `json:"target"`
+	Index    int        `json:"index"`
+	Total    int        `json:"total"`
+	State    buildState `json:"state"`
+	Duration float64    `json:"duration_seconds,omitempty"`
+	Log      string     `json:"log,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// jsonReporter prints one JSON object per line for every state change.
+type jsonReporter struct {
+	progressTimer
+	out io.Writer
+}
+
+func (r *jsonReporter) begin(configs []BuildConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reset(configs)
+	for i, config := range configs {
+		state := statePending
+		if config.UpToDate {
+			state = stateUpToDate
+		}
+		r.emit(i, state, 0, nil)
+	}
+}
+
+func (r *jsonReporter) update(i int, state buildState, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.emit(i, state, r.elapsed(i, state), err)
+}
+
+func (r *jsonReporter) finish() {}
+
+func (r *jsonReporter) emit(i int, state buildState, elapsed time.Duration, err error) {
+	config := r.configs[i]
+	event := buildEvent{
+		Time:     time.Now().Format(time.RFC3339),
+		Target:   config.label(),
+		Index:    i + 1,
+		Total:    len(r.configs),
+		State:    state,
+		Duration: elapsed.Seconds(),
+	}
+	if state != statePending && state != stateUpToDate {
+		event.Log = config.logPath()
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	data, _ := json.Marshal(event)
+	fmt.Fprintln(r.out, string(data))
+}
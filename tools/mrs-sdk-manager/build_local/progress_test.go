@@ -0,0 +1,103 @@
+package buildlocal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestParseOutputModeDefaultsToAuto verifies that omitting --output keeps TTY
+// detection enabled and that unknown modes are rejected.
+func TestParseOutputModeDefaultsToAuto(t *testing.T) {
+	mode, err := ParseOutputMode("")
+	if err != nil || mode != OutputAuto {
+		t.Fatalf("expected empty output mode to default to %q, got %q (error: %v)", OutputAuto, mode, err)
+	}
+	if _, err := ParseOutputMode("xml"); err == nil {
+		t.Fatal("expected unsupported output mode to return an error")
+	}
+}
+
+// TestPlainReporterPrintsOneLinePerStateChange verifies that plain output
+// contains no ANSI cursor movement and reports each state change on its own
+// line, which keeps CI logs readable.
+func TestPlainReporterPrintsOneLinePerStateChange(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := newProgressReporter(OutputPlain, &buf)
+	configs := testReporterConfigs(t)
+
+	reporter.begin(configs)
+	reporter.update(0, stateBuilding, nil)
+	reporter.update(0, stateFailed, errors.New("boom"))
+
+	output := buf.String()
+	if strings.Contains(output, "\033[") {
+		t.Fatalf("expected plain output without ANSI escape codes, got %q", output)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	expectedPrefixes := []string{
+		"[2/2] desktop-desktop-qt6-debug: up-to-date",
+		"[1/2] desktop-desktop-qt5-debug: building",
+		"[1/2] desktop-desktop-qt5-debug: failed",
+	}
+	if len(lines) != len(expectedPrefixes) {
+		t.Fatalf("expected %d lines, got %q", len(expectedPrefixes), output)
+	}
+	for i, prefix := range expectedPrefixes {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Fatalf("expected line %d to start with %q, got %q", i, prefix, lines[i])
+		}
+	}
+}
+
+// TestJSONReporterEmitsParsableEvents verifies that every line of JSON output
+// is a standalone event with the target, state and log path.
+func TestJSONReporterEmitsParsableEvents(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := newProgressReporter(OutputJSON, &buf)
+	configs := testReporterConfigs(t)
+
+	reporter.begin(configs)
+	reporter.update(0, stateBuilding, nil)
+	reporter.update(0, stateSuccess, nil)
+
+	var events []buildEvent
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event buildEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("expected each line to be a JSON object, got %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+
+	expectedStates := []buildState{statePending, stateUpToDate, stateBuilding, stateSuccess}
+	if len(events) != len(expectedStates) {
+		t.Fatalf("expected %d events, got %d", len(expectedStates), len(events))
+	}
+	for i, state := range expectedStates {
+		if events[i].State != state {
+			t.Fatalf("expected event %d to have state %q, got %q", i, state, events[i].State)
+		}
+	}
+
+	last := events[len(events)-1]
+	if last.Target != "desktop-desktop-qt5-debug" || last.Index != 1 || last.Total != 2 {
+		t.Fatalf("unexpected target fields in %+v", last)
+	}
+	if last.Log != "/tmp/mrs-sdk-qt/build/desktop-desktop-qt5-debug/build.log" {
+		t.Fatalf("unexpected log path %q", last.Log)
+	}
+}
+
+// testReporterConfigs returns one target that needs building and one that is
+// already up to date.
+func testReporterConfigs(t *testing.T) []BuildConfig {
+	targets, _ := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{OSes: []string{"desktop"}})
+	return []BuildConfig{
+		{Target: targets[0], BuildDir: "/tmp/mrs-sdk-qt/build/" + targets[0].BuildDir()},
+		{Target: targets[1], BuildDir: "/tmp/mrs-sdk-qt/build/" + targets[1].BuildDir(), UpToDate: true},
+	}
+}
@@ -0,0 +1,65 @@
+package buildlocal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// libProjectPattern matches the project() call in lib/CMakeLists.txt that
+// identifies an mrs-sdk-qt checkout.
+var libProjectPattern = regexp.MustCompile(`(?mi)^\s*project\s*\(\s*mrs-sdk-qt[\s)]`)
+
+// FindRepoRoot returns the root of the mrs-sdk-qt checkout to work on. An
+// explicit repoPath (from --repo) must be the root itself. Otherwise the
+// working directory and its parents are searched. Checkouts are recognized
+// by their files rather than by Git, so forks, mirrors, clones without an
+// origin and source exports all work.
+func FindRepoRoot(repoPath string) (string, error) {
+	if repoPath != "" {
+		root, err := filepath.Abs(repoPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", repoPath, err)
+		}
+		if err := checkRepoRoot(root); err != nil {
+			return "", fmt.Errorf("--repo %s is not an mrs-sdk-qt checkout: %w", repoPath, err)
+		}
+		return root, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		if checkRepoRoot(dir) == nil {
+			return dir, nil
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	return "", fmt.Errorf("%s is not inside an mrs-sdk-qt checkout; run from the checkout or pass --repo", cwd)
+}
+
+// checkRepoRoot verifies that dir contains lib/CMakeLists.txt declaring the
+// mrs-sdk-qt project and the tools/mrs-sdk-manager sources.
+func checkRepoRoot(dir string) error {
+	listsPath := filepath.Join(dir, "lib", "CMakeLists.txt")
+	lists, err := os.ReadFile(listsPath)
+	if err != nil {
+		return fmt.Errorf("%s not found", filepath.Join("lib", "CMakeLists.txt"))
+	}
+	if !libProjectPattern.Match(lists) {
+		return fmt.Errorf("%s does not declare project(mrs-sdk-qt)", listsPath)
+	}
+
+	managerPath := filepath.Join(dir, "tools", "mrs-sdk-manager", "go.mod")
+	if _, err := os.Stat(managerPath); err != nil {
+		return fmt.Errorf("%s not found", filepath.Join("tools", "mrs-sdk-manager", "go.mod"))
+	}
+
+	return nil
+}
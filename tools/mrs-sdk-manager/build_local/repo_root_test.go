@@ -0,0 +1,60 @@
+package buildlocal
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFindRepoRootSearchesUpward verifies that a checkout is recognized by
+// its files alone, without Git or a particular origin, from any of its
+// subdirectories.
+func TestFindRepoRootSearchesUpward(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeTestFile(t, filepath.Join(repoRoot, "lib", "CMakeLists.txt"), "cmake_minimum_required(VERSION 3.16)\n\nPROJECT( mrs-sdk-qt LANGUAGES CXX)\n")
+	writeTestFile(t, filepath.Join(repoRoot, "tools", "mrs-sdk-manager", "go.mod"), "module mrs-sdk-manager\n")
+	subdir := filepath.Join(repoRoot, "lib", "src", "nested")
+	writeTestFile(t, filepath.Join(subdir, "file.cpp"), "")
+
+	t.Chdir(subdir)
+	root, err := FindRepoRoot("")
+	if err != nil {
+		t.Fatalf("expected the checkout to be found from a subdirectory, got error: %v", err)
+	}
+	if root != repoRoot {
+		t.Fatalf("expected root %s, got %s", repoRoot, root)
+	}
+
+	t.Chdir(filepath.Join(repoRoot, "lib"))
+	if root, err := FindRepoRoot(".."); err != nil || root != repoRoot {
+		t.Fatalf("expected --repo .. to select %s, got %s, %v", repoRoot, root, err)
+	}
+	if _, err := FindRepoRoot("src"); err == nil || !strings.Contains(err.Error(), "not an mrs-sdk-qt checkout") {
+		t.Fatalf("expected --repo to require the checkout root, got %v", err)
+	}
+
+	t.Chdir(t.TempDir())
+	if _, err := FindRepoRoot(""); err == nil {
+		t.Fatal("expected a directory outside any checkout to be rejected")
+	}
+}
+
+// TestCheckRepoRootRequiresProjectAndManager verifies that other CMake
+// projects are not mistaken for an mrs-sdk-qt checkout.
+func TestCheckRepoRootRequiresProjectAndManager(t *testing.T) {
+	if err := checkRepoRoot(filepath.Join("..", "..", "..")); err != nil {
+		t.Fatalf("expected this repository to be recognized, got error: %v", err)
+	}
+
+	other := t.TempDir()
+	writeTestFile(t, filepath.Join(other, "lib", "CMakeLists.txt"), "project(mrs-sdk-qt-demo LANGUAGES CXX)\n")
+	writeTestFile(t, filepath.Join(other, "tools", "mrs-sdk-manager", "go.mod"), "module mrs-sdk-manager\n")
+	if err := checkRepoRoot(other); err == nil {
+		t.Fatal("expected a different project name to be rejected")
+	}
+
+	writeTestFile(t, filepath.Join(other, "lib", "CMakeLists.txt"), "project(mrs-sdk-qt)\n")
+	if err := checkRepoRoot(other); err != nil {
+		t.Fatalf("expected project(mrs-sdk-qt) to be accepted, got error: %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package buildlocal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"mrs-sdk-manager/manifest"
+	"mrs-sdk-manager/utils"
+
+	"github.com/fatih/color"
+)
+
+// stagedInstall installs into $MRS_SDK_QT_ROOT/<version> without ever leaving
+// a half-populated version behind. The current tree, minus the replaced
+// paths, is copied into a hidden sibling stage that populate then completes.
+// Only when every step succeeded, including writing the manifest, is the
+// stage renamed into place. The tree it replaces is kept as a hidden sibling,
+// so that it is restored if the swap fails and can be restored later with
+// RollbackInstall; older kept trees are removed. The installed targets are
+// added to the manifest.
+func stagedInstall(sdkRepoRoot, sdkInstallRoot, sdkVersion string, replaced []string, targets []BuildTarget, populate func(stageRoot string) error) (err error) {
+	if err := os.MkdirAll(sdkInstallRoot, 0755); err != nil {
+		return fmt.Errorf("failed to create SDK root directory: %w", err)
+	}
+
+	versionRoot := filepath.Join(sdkInstallRoot, sdkVersion)
+	stageRoot, err := os.MkdirTemp(sdkInstallRoot, "."+sdkVersion+".staging-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(stageRoot)
+		}
+	}()
+	if err := os.Chmod(stageRoot, 0755); err != nil {
+		return err
+	}
+
+	_, statErr := os.Stat(versionRoot)
+	hasPrevious := statErr == nil
+	if hasPrevious {
+		if err := copyInstallTree(versionRoot, stageRoot, replaced); err != nil {
+			return fmt.Errorf("failed to stage the installed version %s: %w", sdkVersion, err)
+		}
+	}
+
+	if err := populate(stageRoot); err != nil {
+		return err
+	}
+	if err := writeInstallManifest(sdkRepoRoot, versionRoot, stageRoot, sdkVersion, targets); err != nil {
+		return fmt.Errorf("failed to write %s: %w", manifest.FileName, err)
+	}
+
+	if !hasPrevious {
+		if err := os.Rename(stageRoot, versionRoot); err != nil {
+			return fmt.Errorf("failed to move the staged installation into place: %w", err)
+		}
+		return nil
+	}
+
+	previousRoot, err := swapIntoPlace(sdkInstallRoot, sdkVersion, stageRoot)
+	if err != nil {
+		return err
+	}
+	color.White("The previous installation is kept in %s; restore it with 'mrs-sdk-manager install --rollback --version %s'", previousRoot, sdkVersion)
+	return nil
+}
+
+// previousInstallPrefix is the name prefix of the trees kept by stagedInstall
+// for rolling back an SDK version. A UTC timestamp follows it, so the newest
+// tree sorts last.
+func previousInstallPrefix(sdkVersion string) string {
+	return "." + sdkVersion + ".previous-"
+}
+
+// swapIntoPlace renames newRoot to the SDK version and keeps the tree it
+// replaces, which it returns. If the rename fails, the replaced tree is put
+// back. Older kept trees of the version are removed.
+func swapIntoPlace(sdkInstallRoot, sdkVersion, newRoot string) (string, error) {
+	versionRoot := filepath.Join(sdkInstallRoot, sdkVersion)
+	previousRoot := filepath.Join(sdkInstallRoot, previousInstallPrefix(sdkVersion)+time.Now().UTC().Format("20060102T150405.000000000"))
+	if err := os.Rename(versionRoot, previousRoot); err != nil {
+		return "", fmt.Errorf("failed to move the installed version %s aside: %w", sdkVersion, err)
+	}
+	if err := os.Rename(newRoot, versionRoot); err != nil {
+		if rollbackErr := os.Rename(previousRoot, versionRoot); rollbackErr != nil {
+			return "", fmt.Errorf("failed to move %s into place: %w; the previous installation is in %s", newRoot, err, previousRoot)
+		}
+		return "", fmt.Errorf("failed to move %s into place: %w", newRoot, err)
+	}
+
+	kept, err := previousInstalls(sdkInstallRoot, sdkVersion)
+	if err != nil {
+		return previousRoot, err
+	}
+	for _, path := range kept {
+		if path == previousRoot {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			color.Yellow("Warning: failed to remove the older installation in %s: %v", path, err)
+		}
+	}
+	return previousRoot, nil
+}
+
+// previousInstalls returns the trees kept for rolling back an SDK version,
+// oldest first.
+func previousInstalls(sdkInstallRoot, sdkVersion string) ([]string, error) {
+	entries, err := os.ReadDir(sdkInstallRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), previousInstallPrefix(sdkVersion)) {
+			paths = append(paths, filepath.Join(sdkInstallRoot, entry.Name()))
+		}
+	}
+	slices.Sort(paths)
+	return paths, nil
+}
+
+// RollbackInstall restores the tree that the last install of an SDK version
+// replaced. The current tree is kept in its place, so a second rollback
+// undoes the first.
+func RollbackInstall(sdkVersion string) error {
+	if err := checkVersionName(sdkVersion); err != nil {
+		return err
+	}
+	sdkInstallRoot, err := utils.ResolveSDKInstallRoot()
+	if err != nil {
+		return err
+	}
+
+	kept, err := previousInstalls(sdkInstallRoot, sdkVersion)
+	if err != nil {
+		return err
+	}
+	if len(kept) == 0 {
+		return fmt.Errorf("no previous installation of SDK version %s is kept in %s", sdkVersion, sdkInstallRoot)
+	}
+	if _, err := os.Stat(filepath.Join(sdkInstallRoot, sdkVersion)); err != nil {
+		return fmt.Errorf("SDK version %s is not installed in %s: %w", sdkVersion, sdkInstallRoot, err)
+	}
+
+	utils.PrintTaskStart(fmt.Sprintf("Rolling back SDK version %s...", sdkVersion))
+	replacedRoot, err := swapIntoPlace(sdkInstallRoot, sdkVersion, kept[len(kept)-1])
+	if err != nil {
+		return err
+	}
+	color.White("The replaced installation is kept in %s", replacedRoot)
+	utils.PrintSuccess(fmt.Sprintf("Restored the previous installation of SDK version %s", sdkVersion))
+	return nil
+}
+
+// copyInstallTree copies an installed SDK version from src to dst, except for
+// the skipped paths relative to src, which are installed again in full.
+// Files are copied rather than linked so that the stage never writes into the
+// previous tree.
+func copyInstallTree(src, dst string, skipped []string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+		if slices.Contains(skipped, relPath) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		target := filepath.Join(dst, relPath)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.Mkdir(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(target, data, info.Mode().Perm())
+		}
+	})
+}
+
+// writeInstallManifest records every file in the stage. Files carried over
+// unchanged from an installed version keep the hash of its manifest, so that
+// an install never hides changes made to the previous tree by hand.
+func writeInstallManifest(sdkRepoRoot, versionRoot, stageRoot, sdkVersion string, targets []BuildTarget) error {
+	files, err := manifest.HashFiles(stageRoot)
+	if err != nil {
+		return err
+	}
+
+	installManifest := &manifest.Manifest{}
+	previous, err := manifest.Read(versionRoot)
+	switch {
+	case err == nil:
+		installManifest.Targets = previous.Targets
+		previousFiles, err := manifest.HashFiles(versionRoot)
+		if err != nil {
+			return err
+		}
+		for path, hash := range files {
+			if previousFiles[path] != hash {
+				continue
+			}
+			if recorded, ok := previous.Files[path]; ok {
+				files[path] = recorded
+			} else {
+				delete(files, path)
+			}
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	installManifest.SDKVersion = sdkVersion
+	installManifest.Commit = resolveBuildInfo(sdkRepoRoot).Commit
+	installManifest.ManagerVersion = utils.ManagerVersion()
+	installManifest.InstalledAt = time.Now().UTC().Truncate(time.Second)
+	installManifest.Files = files
+	for _, target := range targets {
+		installManifest.AddTargets(manifest.Target{
+			Name:      target.Name(),
+			BuildType: strings.ToLower(target.BuildType),
+			LibDir:    filepath.ToSlash(target.InstLibDir()),
+		})
+	}
+
+	return installManifest.Write(stageRoot)
+}
@@ -0,0 +1,199 @@
+package buildlocal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"mrs-sdk-manager/manifest"
+)
+
+// TestInstallBuildsLeavesPreviousVersionOnFailure verifies that an install
+// that fails halfway through neither touches the installed version nor
+// leaves a partial version or staging directory behind.
+func TestInstallBuildsLeavesPreviousVersionOnFailure(t *testing.T) {
+	repoRoot := t.TempDir()
+	sdkRoot := filepath.Join(t.TempDir(), "sdk")
+	t.Setenv("MRS_SDK_QT_ROOT", sdkRoot)
+	initTestRepo(t, repoRoot)
+	createFakeSDKRepo(t, repoRoot)
+
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{Targets: []string{"desktop-desktop-qt5", "desktop-desktop-qt6"}})
+	if err != nil {
+		t.Fatalf("expected selection to succeed, got error: %v", err)
+	}
+	if err := os.Remove(libraryInstallFile(targets[1], repoRoot, "").Src); err != nil {
+		t.Fatalf("failed to remove artifact: %v", err)
+	}
+
+	if err := InstallBuilds(repoRoot, targets); err == nil {
+		t.Fatal("expected InstallBuilds to fail for a missing artifact")
+	}
+	assertSDKRootEntries(t, sdkRoot)
+
+	versionRoot := filepath.Join(sdkRoot, "0.0.0")
+	previousLib := filepath.Join(versionRoot, targets[1].InstLibDir(), "libmrs-sdk-qt.a")
+	writeTestFile(t, previousLib, "previous install")
+	writeTestFile(t, filepath.Join(versionRoot, "include", "mrs-sdk-qt", "removed.hpp"), "previous header")
+
+	if err := InstallBuilds(repoRoot, targets); err == nil {
+		t.Fatal("expected InstallBuilds to fail for a missing artifact")
+	}
+	assertSDKRootEntries(t, sdkRoot, "0.0.0")
+	assertFileExists(t, previousLib)
+	assertFileMissing(t, filepath.Join(versionRoot, targets[0].InstLibDir(), "libmrs-sdk-qt.a"))
+	assertFileMissing(t, filepath.Join(versionRoot, "include", "mrs-sdk-qt", "sdk.hpp"))
+
+	if err := InstallBuilds(repoRoot, targets[:1]); err != nil {
+		t.Fatalf("InstallBuilds returned error: %v", err)
+	}
+	assertSDKRootEntries(t, sdkRoot, filepath.Base(assertOnePreviousInstall(t, sdkRoot, "0.0.0")), "0.0.0")
+	assertFileExists(t, previousLib)
+	assertFileExists(t, filepath.Join(versionRoot, targets[0].InstLibDir(), "libmrs-sdk-qt.a"))
+	assertFileExists(t, filepath.Join(versionRoot, "include", "mrs-sdk-qt", "sdk.hpp"))
+	assertFileMissing(t, filepath.Join(versionRoot, "include", "mrs-sdk-qt", "removed.hpp"))
+}
+
+// TestInstallBuildsWritesManifest verifies that every install records the
+// installed files and targets, and that reinstalling other targets keeps
+// reporting a library that was edited by hand.
+func TestInstallBuildsWritesManifest(t *testing.T) {
+	repoRoot := t.TempDir()
+	sdkRoot := filepath.Join(t.TempDir(), "sdk")
+	t.Setenv("MRS_SDK_QT_ROOT", sdkRoot)
+	initTestRepo(t, repoRoot)
+	createFakeSDKRepo(t, repoRoot)
+	runGit(t, repoRoot, "commit", "-m", "Initial commit")
+
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{Targets: []string{"desktop-desktop-qt5", "desktop-desktop-qt6"}})
+	if err != nil {
+		t.Fatalf("expected selection to succeed, got error: %v", err)
+	}
+	if err := InstallBuilds(repoRoot, targets[:1]); err != nil {
+		t.Fatalf("InstallBuilds returned error: %v", err)
+	}
+
+	versionRoot := filepath.Join(sdkRoot, "0.0.0")
+	editedLib := filepath.Join(versionRoot, targets[0].InstLibDir(), "libmrs-sdk-qt.a")
+	writeTestFile(t, editedLib, "edited by hand")
+	if err := InstallBuilds(repoRoot, targets[1:]); err != nil {
+		t.Fatalf("InstallBuilds returned error: %v", err)
+	}
+
+	installManifest, err := manifest.Read(versionRoot)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if installManifest.SDKVersion != "0.0.0" || installManifest.Commit != resolveBuildInfo(repoRoot).Commit || installManifest.ManagerVersion == "" {
+		t.Fatalf("unexpected manifest: %+v", installManifest)
+	}
+	if len(installManifest.Targets) != 2 || installManifest.Targets[0].Name != "desktop-desktop-qt5" || installManifest.Targets[1].LibDir != filepath.ToSlash(targets[1].InstLibDir()) {
+		t.Fatalf("expected both installed targets, got %+v", installManifest.Targets)
+	}
+	if _, ok := installManifest.Files["include/mrs-sdk-qt/sdk.hpp"]; !ok {
+		t.Fatalf("expected the headers to be recorded, got %v", installManifest.Files)
+	}
+
+	differences, err := installManifest.Compare(versionRoot)
+	if err != nil {
+		t.Fatalf("Compare returned error: %v", err)
+	}
+	if !slices.Equal(differences.Modified, []string{filepath.ToSlash(filepath.Join(targets[0].InstLibDir(), "libmrs-sdk-qt.a"))}) || len(differences.Missing) != 0 || len(differences.Unexpected) != 0 {
+		t.Fatalf("expected only the edited library to be reported, got %+v", differences)
+	}
+}
+
+// TestInstallBuildsKeepsPreviousTreeForRollback verifies that overwriting a
+// version keeps the tree it replaced, that only the newest one is kept, and
+// that a rollback restores it and can itself be undone.
+func TestInstallBuildsKeepsPreviousTreeForRollback(t *testing.T) {
+	repoRoot := t.TempDir()
+	sdkRoot := filepath.Join(t.TempDir(), "sdk")
+	t.Setenv("MRS_SDK_QT_ROOT", sdkRoot)
+	initTestRepo(t, repoRoot)
+	createFakeSDKRepo(t, repoRoot)
+
+	if err := RollbackInstall("0.0.0"); err == nil {
+		t.Fatal("expected a rollback without a kept installation to fail")
+	}
+
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{Targets: []string{"desktop-desktop-qt5", "desktop-desktop-qt6"}})
+	if err != nil {
+		t.Fatalf("expected selection to succeed, got error: %v", err)
+	}
+	versionRoot := filepath.Join(sdkRoot, "0.0.0")
+	firstLib := filepath.Join(versionRoot, targets[0].InstLibDir(), "libmrs-sdk-qt.a")
+	secondLib := filepath.Join(versionRoot, targets[1].InstLibDir(), "libmrs-sdk-qt.a")
+
+	if err := InstallBuilds(repoRoot, targets[:1]); err != nil {
+		t.Fatalf("InstallBuilds returned error: %v", err)
+	}
+	assertSDKRootEntries(t, sdkRoot, "0.0.0")
+	writeTestFile(t, filepath.Join(versionRoot, "marker"), "first install")
+
+	if err := InstallBuilds(repoRoot, targets[1:]); err != nil {
+		t.Fatalf("InstallBuilds returned error: %v", err)
+	}
+	previousRoot := assertOnePreviousInstall(t, sdkRoot, "0.0.0")
+	assertFileExists(t, filepath.Join(previousRoot, targets[0].InstLibDir(), "libmrs-sdk-qt.a"))
+	assertFileMissing(t, filepath.Join(previousRoot, targets[1].InstLibDir(), "libmrs-sdk-qt.a"))
+	assertFileExists(t, secondLib)
+
+	if err := InstallBuilds(repoRoot, targets[1:]); err != nil {
+		t.Fatalf("InstallBuilds returned error: %v", err)
+	}
+	newestRoot := assertOnePreviousInstall(t, sdkRoot, "0.0.0")
+	assertFileExists(t, filepath.Join(newestRoot, targets[1].InstLibDir(), "libmrs-sdk-qt.a"))
+
+	if err := RollbackInstall("0.0.0"); err != nil {
+		t.Fatalf("RollbackInstall returned error: %v", err)
+	}
+	assertFileExists(t, firstLib)
+	assertFileExists(t, secondLib)
+	assertOnePreviousInstall(t, sdkRoot, "0.0.0")
+
+	// A second rollback undoes the first.
+	if err := RollbackInstall("0.0.0"); err != nil {
+		t.Fatalf("RollbackInstall returned error: %v", err)
+	}
+	assertFileExists(t, secondLib)
+	assertFileExists(t, filepath.Join(versionRoot, "marker"))
+
+	if err := RollbackInstall("../0.0.0"); err == nil {
+		t.Fatal("expected an invalid version to be rejected")
+	}
+}
+
+// assertOnePreviousInstall fails the test unless exactly one tree is kept for
+// rolling back the version, and returns it.
+func assertOnePreviousInstall(t *testing.T, sdkRoot, sdkVersion string) string {
+	t.Helper()
+
+	kept, err := previousInstalls(sdkRoot, sdkVersion)
+	if err != nil {
+		t.Fatalf("previousInstalls returned error: %v", err)
+	}
+	if len(kept) != 1 {
+		t.Fatalf("expected one previous installation of %s, got %v", sdkVersion, kept)
+	}
+	return kept[0]
+}
+
+// assertSDKRootEntries fails the test unless the SDK root holds exactly the
+// given entries, e.g. no staging trees were left behind.
+func assertSDKRootEntries(t *testing.T, sdkRoot string, expected ...string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(sdkRoot)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", sdkRoot, err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if !slices.Equal(names, expected) {
+		t.Fatalf("expected %s to contain %v, got %v", sdkRoot, expected, names)
+	}
+}
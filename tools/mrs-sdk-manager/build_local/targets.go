@@ -3,32 +3,76 @@ package buildlocal
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
-// BuildTarget represents the parameters for a specific build configuration
+// BuildTarget represents the parameters for a specific build configuration.
+// Everything except BuildType is read from an entry of lib/targets.yaml.
 type BuildTarget struct {
-	QtVersion string // "qt5" or "qt6"
-	OS        string // "yocto", "buildroot", "desktop"
-	System    string // "linux" only
-	Processor string // "x86_64", "arm"
-	Device    string // "mconn", "fusion", "desktop"
-	BuildType string // "debug"
+	QtVersion string `yaml:"qt"`        // "qt5" or "qt6"
+	OS        string `yaml:"os"`        // "yocto", "buildroot", "desktop"
+	System    string `yaml:"system"`    // "linux" only
+	Processor string `yaml:"processor"` // "x86_64", "arm", "aarch64"
+	Device    string `yaml:"device"`    // "mconn", "fusion", "neuralplex", "desktop"
+	BuildType string `yaml:"-"`         // "debug", "release", "relwithdebinfo"
+
+	Toolchain         string    `yaml:"toolchain"`           // Toolchain helper, e.g. "yocto-qt5"
+	ExpectedQtVersion string    `yaml:"expected_qt_version"` // Qt version the toolchain helper expects
+	CompilerTarget    string    `yaml:"compiler_target"`     // Compiler target triple, Yocto only
+	FloatABI          string    `yaml:"float_abi"`           // "hard" or "soft", ARM only
+	Env               TargetEnv `yaml:"env"`
+}
+
+// TargetEnv names the env config keys that configure a target's kit. Empty
+// fields are not used by the target's OS.
+type TargetEnv struct {
+	Sysroot        string `yaml:"sysroot"`
+	CXXCompiler    string `yaml:"cxx_compiler"`
+	EnvSetupScript string `yaml:"env_setup_script"`
+	QtPrefix       string `yaml:"qt_prefix"`
+	TestRunner     string `yaml:"test_runner"` // Optional emulator for cross-compiled tests
+}
+
+// Name identifies the target without its build type, e.g. "mconn-yocto-qt5".
+func (b *BuildTarget) Name() string {
+	return fmt.Sprintf("%s-%s-%s", b.Device, b.OS, b.QtVersion)
 }
 
 func (b *BuildTarget) BuildDir() string {
-	return fmt.Sprintf("%s-%s-%s-%s", b.Device, b.OS, b.QtVersion, strings.ToLower(b.BuildType))
+	return fmt.Sprintf("%s-%s", b.Name(), strings.ToLower(b.BuildType))
 }
 
 func (b *BuildTarget) InstTreeDir() string {
 	return filepath.Join("lib", b.QtVersion, b.OS, fmt.Sprintf("%s_%s_%s", b.System, b.Processor, b.Device))
 }
 
-func AllBuildTargets() []BuildTarget {
+// InstLibDir is the directory inside an SDK version that holds the static
+// library for this target's build type. The config.cmake/config.pri helpers
+// resolve the same path from the consumer's build type.
+func (b *BuildTarget) InstLibDir() string {
+	return filepath.Join(b.InstTreeDir(), strings.ToLower(b.BuildType))
+}
+
+// CMakeBuildType returns the canonical CMAKE_BUILD_TYPE spelling for the
+// target's build type.
+func (b *BuildTarget) CMakeBuildType() string {
+	switch strings.ToLower(b.BuildType) {
+	case "release":
+		return "Release"
+	case "relwithdebinfo":
+		return "RelWithDebInfo"
+	default:
+		return "Debug"
+	}
+}
+
+// AllBuildTargets returns every target in the matrix for every build type.
+func (m *TargetMatrix) AllBuildTargets() []BuildTarget {
 	var allTargets []BuildTarget
 
-	for _, target := range validTargets {
-		for _, buildType := range validBuildTypes {
+	for _, buildType := range validBuildTypes {
+		for _, target := range m.Targets {
 			target.BuildType = buildType
 			allTargets = append(allTargets, target)
 		}
@@ -37,46 +81,149 @@ func AllBuildTargets() []BuildTarget {
 	return allTargets
 }
 
-var validBuildTypes = [...]string{
-	"debug",
+// NamedBuildTargets resolves target arguments such as "mconn-yocto-qt5-debug"
+// against the matrix. A target name without build type, e.g.
+// "mconn-yocto-qt5", selects every build type of that target.
+func (m *TargetMatrix) NamedBuildTargets(targetArgs []string) ([]BuildTarget, error) {
+	var targets []BuildTarget
+	for _, targetArg := range targetArgs {
+		found := false
+		for _, target := range m.AllBuildTargets() {
+			if target.BuildDir() != targetArg && target.Name() != targetArg {
+				continue
+			}
+			found = true
+			if !slices.Contains(targets, target) {
+				targets = append(targets, target)
+			}
+		}
+		if !found {
+			var names []string
+			for _, target := range m.Targets {
+				names = append(names, target.Name())
+			}
+			return nil, fmt.Errorf("unknown target %q (targets: %s)", targetArg, strings.Join(names, ", "))
+		}
+	}
+
+	return targets, nil
+}
+
+// TargetFilter narrows the build matrix. Empty fields match everything.
+type TargetFilter struct {
+	Devices    []string // e.g. "mconn"
+	OSes       []string // e.g. "yocto"
+	QtVersions []string // e.g. "qt5"
+	Targets    []string // BuildTarget.Name() or BuildTarget.BuildDir() values
+}
+
+// Matches reports whether the target passes every dimension of the filter.
+func (f TargetFilter) Matches(b BuildTarget) bool {
+	if len(f.Devices) > 0 && !slices.Contains(f.Devices, b.Device) {
+		return false
+	}
+	if len(f.OSes) > 0 && !slices.Contains(f.OSes, b.OS) {
+		return false
+	}
+	if len(f.QtVersions) > 0 && !slices.Contains(f.QtVersions, b.QtVersion) {
+		return false
+	}
+	if len(f.Targets) > 0 && !slices.Contains(f.Targets, b.Name()) && !slices.Contains(f.Targets, b.BuildDir()) {
+		return false
+	}
+	return true
 }
 
-// The order of this slice should NOT change!!!
-// These are the targets minus build type.
-var validTargets = [...]BuildTarget{
-	{
-		QtVersion: "qt5",
-		OS:        "yocto",
-		System:    "linux",
-		Processor: "arm",
-		Device:    "mconn",
-	},
-	{
-		QtVersion: "qt5",
-		OS:        "buildroot",
-		System:    "linux",
-		Processor: "arm",
-		Device:    "mconn",
-	},
-	{
-		QtVersion: "qt5",
-		OS:        "buildroot",
-		System:    "linux",
-		Processor: "arm",
-		Device:    "fusion",
-	},
-	{
-		QtVersion: "qt5",
-		OS:        "desktop",
-		System:    "linux",
-		Processor: "x86_64",
-		Device:    "desktop",
-	},
-	{
-		QtVersion: "qt6",
-		OS:        "desktop",
-		System:    "linux",
-		Processor: "x86_64",
-		Device:    "desktop",
-	},
+// validate rejects filter values that can never match a target so that typos
+// fail fast instead of silently building nothing.
+func (f TargetFilter) validate(matrix *TargetMatrix) error {
+	var devices, oses, qtVersions, names []string
+	for _, target := range matrix.AllBuildTargets() {
+		devices = append(devices, target.Device)
+		oses = append(oses, target.OS)
+		qtVersions = append(qtVersions, target.QtVersion)
+		names = append(names, target.Name(), target.BuildDir())
+	}
+
+	checks := []struct {
+		flag   string
+		values []string
+		valid  []string
+	}{
+		{"device", f.Devices, devices},
+		{"os", f.OSes, oses},
+		{"qt", f.QtVersions, qtVersions},
+		{"target", f.Targets, names},
+	}
+	for _, check := range checks {
+		for _, value := range check.values {
+			if !slices.Contains(check.valid, value) {
+				return fmt.Errorf("invalid --%s value %q (expected one of: %s)", check.flag, value, strings.Join(uniqueSorted(check.valid), ", "))
+			}
+		}
+	}
+
+	return nil
+}
+
+// SelectBuildTargets returns every target in the matrix that matches the
+// filter for each of the given build types. Targets are grouped by build type
+// in the order given.
+func (m *TargetMatrix) SelectBuildTargets(buildTypes []string, filter TargetFilter) ([]BuildTarget, error) {
+	if err := filter.validate(m); err != nil {
+		return nil, err
+	}
+
+	var targets []BuildTarget
+	for _, buildType := range buildTypes {
+		for _, target := range m.Targets {
+			target.BuildType = buildType
+			if filter.Matches(target) {
+				targets = append(targets, target)
+			}
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no build targets match the selected filters")
+	}
+
+	return targets, nil
+}
+
+func uniqueSorted(values []string) []string {
+	out := slices.Clone(values)
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// ParseBuildTypes validates the values passed to `build-local --build-type`.
+// Duplicates are dropped and an empty list falls back to the historical
+// debug-only behavior.
+func ParseBuildTypes(raw []string) ([]string, error) {
+	var buildTypes []string
+	for _, value := range raw {
+		buildType := strings.ToLower(strings.TrimSpace(value))
+		if buildType == "" {
+			continue
+		}
+		if !slices.Contains(validBuildTypes[:], buildType) {
+			return nil, fmt.Errorf("invalid build type %q (expected one of: %s)", value, strings.Join(validBuildTypes[:], ", "))
+		}
+		if !slices.Contains(buildTypes, buildType) {
+			buildTypes = append(buildTypes, buildType)
+		}
+	}
+
+	if len(buildTypes) == 0 {
+		return []string{"debug"}, nil
+	}
+
+	return buildTypes, nil
+}
+
+var validBuildTypes = [...]string{
+	"debug",
+	"release",
+	"relwithdebinfo",
 }
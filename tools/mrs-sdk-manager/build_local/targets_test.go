@@ -0,0 +1,123 @@
+package buildlocal
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestParseBuildTypesDefaultsToDebug verifies that omitting --build-type keeps
+// the historical debug-only build instead of compiling every configuration.
+func TestParseBuildTypesDefaultsToDebug(t *testing.T) {
+	buildTypes, err := ParseBuildTypes(nil)
+	if err != nil {
+		t.Fatalf("expected empty build types to default successfully, got error: %v", err)
+	}
+	if !slices.Equal(buildTypes, []string{"debug"}) {
+		t.Fatalf("expected default build types to be [debug], got %v", buildTypes)
+	}
+}
+
+// TestParseBuildTypesNormalizesInput verifies that build types are accepted in
+// any case and that repeated values do not schedule duplicate builds.
+func TestParseBuildTypesNormalizesInput(t *testing.T) {
+	buildTypes, err := ParseBuildTypes([]string{"Release", "debug", " RelWithDebInfo ", "release"})
+	if err != nil {
+		t.Fatalf("expected build types to parse successfully, got error: %v", err)
+	}
+	expected := []string{"release", "debug", "relwithdebinfo"}
+	if !slices.Equal(buildTypes, expected) {
+		t.Fatalf("expected build types %v, got %v", expected, buildTypes)
+	}
+}
+
+// TestParseBuildTypesRejectsUnsupportedTypes verifies that typos fail fast
+// instead of passing an unknown CMAKE_BUILD_TYPE through to CMake.
+func TestParseBuildTypesRejectsUnsupportedTypes(t *testing.T) {
+	if _, err := ParseBuildTypes([]string{"minsizerel"}); err == nil {
+		t.Fatal("expected unsupported build type to return an error")
+	}
+}
+
+// TestSelectBuildTargetsSeparatesBuildTypes verifies that each build type gets
+// its own build directory and installation subdirectory so that debug and
+// release libraries never overwrite each other.
+func TestSelectBuildTargetsSeparatesBuildTypes(t *testing.T) {
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug", "release"}, TargetFilter{})
+	if err != nil {
+		t.Fatalf("expected unfiltered selection to succeed, got error: %v", err)
+	}
+	if len(targets) != 2*len(testTargetMatrix(t).Targets) {
+		t.Fatalf("expected %d targets, got %d", 2*len(testTargetMatrix(t).Targets), len(targets))
+	}
+
+	buildDirs := map[string]struct{}{}
+	instLibDirs := map[string]struct{}{}
+	for _, target := range targets {
+		buildDirs[target.BuildDir()] = struct{}{}
+		instLibDirs[target.InstLibDir()] = struct{}{}
+		if filepath.Dir(target.InstLibDir()) != target.InstTreeDir() {
+			t.Fatalf("expected %s to be nested in %s", target.InstLibDir(), target.InstTreeDir())
+		}
+	}
+	if len(buildDirs) != len(targets) || len(instLibDirs) != len(targets) {
+		t.Fatalf("expected unique build and install directories for every target")
+	}
+}
+
+// TestSelectBuildTargetsAppliesFilter verifies that the device/OS/Qt filters
+// and the --target selector narrow the matrix, so desktop-only developers do
+// not need cross toolchains installed.
+func TestSelectBuildTargetsAppliesFilter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filter   TargetFilter
+		expected []string
+	}{
+		{
+			name:     "os",
+			filter:   TargetFilter{OSes: []string{"desktop"}},
+			expected: []string{"desktop-desktop-qt5-debug", "desktop-desktop-qt6-debug"},
+		},
+		{
+			name:     "device and qt",
+			filter:   TargetFilter{Devices: []string{"mconn"}, QtVersions: []string{"qt5"}},
+			expected: []string{"mconn-yocto-qt5-debug", "mconn-buildroot-qt5-debug"},
+		},
+		{
+			name:     "target name",
+			filter:   TargetFilter{Targets: []string{"fusion-buildroot-qt5"}},
+			expected: []string{"fusion-buildroot-qt5-debug"},
+		},
+		{
+			name:     "target build dir",
+			filter:   TargetFilter{Targets: []string{"mconn-yocto-qt5-debug"}},
+			expected: []string{"mconn-yocto-qt5-debug"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, testCase.filter)
+		if err != nil {
+			t.Fatalf("%s: expected selection to succeed, got error: %v", testCase.name, err)
+		}
+		var buildDirs []string
+		for _, target := range targets {
+			buildDirs = append(buildDirs, target.BuildDir())
+		}
+		if !slices.Equal(buildDirs, testCase.expected) {
+			t.Fatalf("%s: expected targets %v, got %v", testCase.name, testCase.expected, buildDirs)
+		}
+	}
+}
+
+// TestSelectBuildTargetsRejectsUnknownOrEmptySelections verifies that typos in
+// filter values and combinations that match nothing are reported as errors.
+func TestSelectBuildTargetsRejectsUnknownOrEmptySelections(t *testing.T) {
+	if _, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{Devices: []string{"mcon"}}); err == nil {
+		t.Fatal("expected unknown device to return an error")
+	}
+	if _, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{Devices: []string{"fusion"}, OSes: []string{"yocto"}}); err == nil {
+		t.Fatal("expected empty selection to return an error")
+	}
+}
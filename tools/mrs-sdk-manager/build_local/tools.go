@@ -0,0 +1,320 @@
+package buildlocal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"mrs-sdk-manager/env"
+)
+
+// Minimum tool versions. lib/CMakeLists.txt requires CMake 3.16, and the test
+// scope runs ctest with --test-dir and --output-junit, which need 3.21.
+var (
+	minCMakeVersion = []int{3, 16}
+	minCTestVersion = []int{3, 21}
+	minNinjaVersion = []int{1, 3}
+)
+
+// minCompilerVersions are the oldest GCC versions that Qt supports, by the Qt
+// version of the target: Qt 5.15 needs GCC 5, Qt 6 needs GCC 9 for C++17.
+var minCompilerVersions = map[string][]int{
+	"qt5": {5},
+	"qt6": {9},
+}
+
+var toolVersionRegex = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// buildTool is an external program resolved for a build-local run.
+type buildTool struct {
+	Name    string // e.g. "cmake" or the env key of a compiler
+	Path    string
+	Version string
+}
+
+// buildTools holds the programs that the build configurations invoke.
+type buildTools struct {
+	CMake     buildTool
+	CTest     buildTool // Only resolved for the test scope
+	Generator string    // CMake generator, "Ninja" or "Unix Makefiles"
+	Make      buildTool // Program that runs the generated build files
+	Compilers []buildTool
+}
+
+// generatorArgs returns the CMake cache entries that select the generator and
+// the exact program that runs it.
+func (t buildTools) generatorArgs() []string {
+	return []string{
+		"-DCMAKE_GENERATOR:STRING=" + t.Generator,
+		"-DCMAKE_MAKE_PROGRAM:FILEPATH=" + t.Make.Path,
+	}
+}
+
+// print reports the resolved tools so that a failing build can be traced to
+// the programs it used.
+func (t buildTools) print(out io.Writer) {
+	tools := []buildTool{t.CMake}
+	if t.CTest.Path != "" {
+		tools = append(tools, t.CTest)
+	}
+	tools = append(tools, t.Make)
+	tools = append(tools, t.Compilers...)
+
+	fmt.Fprintf(out, "Generator: %s\n", t.Generator)
+	for _, tool := range tools {
+		name := tool.Name
+		if tool.Version != "" {
+			name += " " + tool.Version
+		}
+		fmt.Fprintf(out, "%s: %s\n", name, tool.Path)
+	}
+}
+
+// resolveBuildTools finds cmake, the build program and the C++ compilers of
+// the selected targets, and checks their versions. cmake and ninja are looked
+// up in their env config key, then on the PATH, then in the Qt Tools
+// directories. Without a usable ninja the build falls back to Unix Makefiles.
+func resolveBuildTools(envConfig map[string]string, scope BuildScope, targets []BuildTarget) (buildTools, error) {
+	var tools buildTools
+	toolsDirs := qtToolsDirs(envConfig)
+
+	cmake, err := findTool("cmake", env.CMAKE_PROGRAM.Key, envConfig, toolsDirs, "CMake/bin")
+	if err != nil {
+		return tools, err
+	}
+	if tools.CMake, err = checkToolVersion(cmake, minCMakeVersion); err != nil {
+		return tools, err
+	}
+
+	if scope.IncludesTests() {
+		// ctest has to match the cmake that configured the tests.
+		ctest := buildTool{Name: "ctest", Path: filepath.Join(filepath.Dir(tools.CMake.Path), "ctest")}
+		if tools.CTest, err = checkToolVersion(ctest, minCTestVersion); err != nil {
+			return tools, err
+		}
+	}
+
+	if tools.Generator, tools.Make, err = resolveGenerator(envConfig, toolsDirs); err != nil {
+		return tools, err
+	}
+
+	if tools.Compilers, err = checkCompilers(envConfig, targets); err != nil {
+		return tools, err
+	}
+
+	return tools, nil
+}
+
+// locateBuildTools finds the same programs as resolveBuildTools without
+// running any of them, for dry runs. Versions are left empty, and a program
+// that cannot be found keeps its bare name as path and is reported in the
+// returned errors instead of failing.
+func locateBuildTools(envConfig map[string]string, scope BuildScope, targets []BuildTarget) (buildTools, []error) {
+	var tools buildTools
+	var missing []error
+	toolsDirs := qtToolsDirs(envConfig)
+
+	cmake, err := findTool("cmake", env.CMAKE_PROGRAM.Key, envConfig, toolsDirs, "CMake/bin")
+	if err != nil {
+		cmake, missing = buildTool{Name: "cmake", Path: "cmake"}, append(missing, err)
+	}
+	tools.CMake = cmake
+	if scope.IncludesTests() {
+		tools.CTest = buildTool{Name: "ctest", Path: filepath.Join(filepath.Dir(cmake.Path), "ctest")}
+	}
+
+	tools.Generator = "Ninja"
+	ninja, err := findTool("ninja", env.NINJA_PROGRAM.Key, envConfig, toolsDirs, "Ninja")
+	switch {
+	case err == nil:
+		tools.Make = ninja
+	case envConfig[env.NINJA_PROGRAM.Key] != "":
+		tools.Make, missing = buildTool{Name: "ninja", Path: "ninja"}, append(missing, err)
+	default:
+		if makePath, makeErr := exec.LookPath("make"); makeErr == nil {
+			tools.Generator, tools.Make = "Unix Makefiles", buildTool{Name: "make", Path: makePath}
+		} else {
+			tools.Make, missing = buildTool{Name: "ninja", Path: "ninja"}, append(missing, err)
+		}
+	}
+
+	for _, target := range targets {
+		key := target.Env.CXXCompiler
+		if slices.ContainsFunc(tools.Compilers, func(c buildTool) bool { return c.Name == key }) {
+			continue
+		}
+		if path := envConfig[key]; !isExecutableFile(path) {
+			missing = append(missing, fmt.Errorf("%s (%s) is not an executable file", key, path))
+		}
+		tools.Compilers = append(tools.Compilers, buildTool{Name: key, Path: envConfig[key]})
+	}
+
+	return tools, missing
+}
+
+// resolveGenerator selects Ninja when a usable ninja is found and falls back
+// to Unix Makefiles otherwise. It returns the generator and the program that
+// runs it.
+func resolveGenerator(envConfig map[string]string, toolsDirs []string) (string, buildTool, error) {
+	ninja, err := findTool("ninja", env.NINJA_PROGRAM.Key, envConfig, toolsDirs, "Ninja")
+	if err == nil {
+		ninja, err = checkToolVersion(ninja, minNinjaVersion)
+	}
+	switch {
+	case err == nil:
+		return "Ninja", ninja, nil
+	case envConfig[env.NINJA_PROGRAM.Key] != "":
+		// An explicitly configured ninja must work.
+		return "", buildTool{}, err
+	}
+
+	makePath, err := exec.LookPath("make")
+	if err != nil {
+		return "", buildTool{}, fmt.Errorf("neither ninja nor make was found; install one of them or set %s", env.NINJA_PROGRAM.Key)
+	}
+	makeTool, err := checkToolVersion(buildTool{Name: "make", Path: makePath}, nil)
+	if err != nil {
+		return "", buildTool{}, err
+	}
+	return "Unix Makefiles", makeTool, nil
+}
+
+// qtToolsDirs returns the Qt Tools directories that may contain CMake and
+// Ninja. Qt's online installer puts them next to the Qt versions, e.g.
+// ~/Qt/Tools for a Qt prefix of ~/Qt/6.8.0/gcc_64.
+func qtToolsDirs(envConfig map[string]string) []string {
+	var dirs []string
+	for _, key := range []string{env.DESKTOP_QT6_PREFIX.Key, env.DESKTOP_QT5_PREFIX.Key} {
+		if prefix := envConfig[key]; prefix != "" {
+			dirs = append(dirs, filepath.Join(prefix, "..", "..", "Tools"))
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, "Qt", "Tools"))
+	}
+	dirs = append(dirs, "/opt/Qt/Tools")
+
+	var unique []string
+	for _, dir := range dirs {
+		if dir = filepath.Clean(dir); !slices.Contains(unique, dir) {
+			unique = append(unique, dir)
+		}
+	}
+	return unique
+}
+
+// findTool resolves a program from its env config key, the PATH, or subdir of
+// the Qt Tools directories, in that order.
+func findTool(name, envKey string, envConfig map[string]string, toolsDirs []string, subdir string) (buildTool, error) {
+	if path := envConfig[envKey]; path != "" {
+		if !isExecutableFile(path) {
+			return buildTool{}, fmt.Errorf("%s (%s) is not an executable file", envKey, path)
+		}
+		return buildTool{Name: name, Path: path}, nil
+	}
+
+	if path, err := exec.LookPath(name); err == nil {
+		return buildTool{Name: name, Path: path}, nil
+	}
+
+	for _, dir := range toolsDirs {
+		if path := filepath.Join(dir, subdir, name); isExecutableFile(path) {
+			return buildTool{Name: name, Path: path}, nil
+		}
+	}
+
+	return buildTool{}, fmt.Errorf("%s was not found on the PATH or in the Qt Tools directories (%s); install it or set %s",
+		name, strings.Join(toolsDirs, ", "), envKey)
+}
+
+// checkToolVersion runs `<tool> --version` and verifies that the first
+// version number in its output is at least minVersion.
+func checkToolVersion(tool buildTool, minVersion []int) (buildTool, error) {
+	output, err := exec.Command(tool.Path, "--version").Output()
+	if err != nil {
+		return tool, fmt.Errorf("failed to run %s --version: %w", tool.Path, err)
+	}
+
+	match := toolVersionRegex.FindStringSubmatch(string(output))
+	if match == nil {
+		return tool, fmt.Errorf("failed to parse the version of %s", tool.Path)
+	}
+	tool.Version = strings.Trim(match[0], ".")
+
+	if !versionAtLeast(tool.Version, minVersion) {
+		return tool, fmt.Errorf("%s %s is too old; version %s or newer is required", tool.Path, tool.Version, joinVersion(minVersion))
+	}
+
+	return tool, nil
+}
+
+// checkCompilers verifies that the C++ compiler of every selected target runs
+// and is at least the minimum version for the target's Qt version. A compiler
+// shared by several targets must satisfy the highest of their minimums.
+func checkCompilers(envConfig map[string]string, targets []BuildTarget) ([]buildTool, error) {
+	var keys []string
+	minVersions := map[string][]int{}
+	for _, target := range targets {
+		key := target.Env.CXXCompiler
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+		if minVersion := minCompilerVersions[target.QtVersion]; !versionAtLeast(joinVersion(minVersions[key]), minVersion) {
+			minVersions[key] = minVersion
+		}
+	}
+
+	var compilers []buildTool
+	for _, key := range keys {
+		path := envConfig[key]
+		if !isExecutableFile(path) {
+			return nil, fmt.Errorf("%s (%s) is not an executable file", key, path)
+		}
+		output, err := exec.Command(path, "-dumpversion").Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to run %s -dumpversion: %w", path, err)
+		}
+		compiler := buildTool{Name: key, Path: path, Version: strings.TrimSpace(string(output))}
+		if !versionAtLeast(compiler.Version, minVersions[key]) {
+			return nil, fmt.Errorf("%s %s is too old; version %s or newer is required", compiler.Path, compiler.Version, joinVersion(minVersions[key]))
+		}
+		compilers = append(compilers, compiler)
+	}
+
+	return compilers, nil
+}
+
+// versionAtLeast compares the dotted version against minVersion component by
+// component. Missing components count as zero.
+func versionAtLeast(version string, minVersion []int) bool {
+	parts := strings.Split(version, ".")
+	for i, minPart := range minVersion {
+		part := 0
+		if i < len(parts) {
+			part, _ = strconv.Atoi(parts[i])
+		}
+		if part != minPart {
+			return part > minPart
+		}
+	}
+	return true
+}
+
+func joinVersion(version []int) string {
+	var parts []string
+	for _, part := range version {
+		parts = append(parts, strconv.Itoa(part))
+	}
+	return strings.Join(parts, ".")
+}
+
+func isExecutableFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode()&0111 != 0
+}
@@ -0,0 +1,145 @@
+package buildlocal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestResolveBuildToolsFallsBackToMakefiles verifies that cmake is taken from
+// its env config key and that a missing ninja selects the Unix Makefiles
+// generator instead of failing the build.
+func TestResolveBuildToolsFallsBackToMakefiles(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	t.Setenv("PATH", filepath.Join(root, "bin"))
+	writeTestTool(t, filepath.Join(root, "bin", "make"), "GNU Make 4.3")
+	writeTestTool(t, filepath.Join(root, "cmake", "bin", "cmake"), "cmake version 3.28.1")
+	writeTestTool(t, filepath.Join(root, "cmake", "bin", "ctest"), "ctest version 3.28.1")
+	writeTestTool(t, filepath.Join(root, "g++"), "13")
+
+	envConfig := map[string]string{
+		"CMAKE_PROGRAM":        filepath.Join(root, "cmake", "bin", "cmake"),
+		"DESKTOP_CXX_COMPILER": filepath.Join(root, "g++"),
+	}
+	tools, err := resolveBuildTools(envConfig, BuildScopeTest, testDesktopTargets())
+	if err != nil {
+		t.Fatalf("expected tools to resolve, got error: %v", err)
+	}
+
+	if tools.CMake.Path != envConfig["CMAKE_PROGRAM"] || tools.CMake.Version != "3.28.1" {
+		t.Fatalf("expected cmake from CMAKE_PROGRAM, got %+v", tools.CMake)
+	}
+	if tools.CTest.Path != filepath.Join(root, "cmake", "bin", "ctest") {
+		t.Fatalf("expected ctest next to cmake, got %+v", tools.CTest)
+	}
+	if tools.Generator != "Unix Makefiles" || tools.Make.Path != filepath.Join(root, "bin", "make") {
+		t.Fatalf("expected the Unix Makefiles fallback, got %s with %+v", tools.Generator, tools.Make)
+	}
+	if len(tools.Compilers) != 1 || tools.Compilers[0].Version != "13" {
+		t.Fatalf("expected one desktop compiler, got %+v", tools.Compilers)
+	}
+}
+
+// TestResolveBuildToolsSearchesQtTools verifies that cmake and ninja
+// installed by the Qt online installer are found when they are not on the
+// PATH.
+func TestResolveBuildToolsSearchesQtTools(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	t.Setenv("PATH", filepath.Join(root, "bin"))
+	writeTestTool(t, filepath.Join(root, "Qt", "Tools", "CMake", "bin", "cmake"), "cmake version 3.30.5")
+	writeTestTool(t, filepath.Join(root, "Qt", "Tools", "Ninja", "ninja"), "1.12.1")
+	writeTestTool(t, filepath.Join(root, "g++"), "13")
+
+	envConfig := map[string]string{
+		"DESKTOP_CXX_COMPILER": filepath.Join(root, "g++"),
+		"DESKTOP_QT6_PREFIX":   filepath.Join(root, "Qt", "6.8.0", "gcc_64"),
+	}
+	tools, err := resolveBuildTools(envConfig, BuildScopeLibs, testDesktopTargets())
+	if err != nil {
+		t.Fatalf("expected tools to resolve, got error: %v", err)
+	}
+
+	if tools.CMake.Path != filepath.Join(root, "Qt", "Tools", "CMake", "bin", "cmake") {
+		t.Fatalf("expected cmake from Qt Tools, got %+v", tools.CMake)
+	}
+	if tools.Generator != "Ninja" || tools.Make.Path != filepath.Join(root, "Qt", "Tools", "Ninja", "ninja") {
+		t.Fatalf("expected ninja from Qt Tools, got %s with %+v", tools.Generator, tools.Make)
+	}
+	if tools.CTest.Path != "" {
+		t.Fatalf("expected ctest to be resolved only for the test scope, got %+v", tools.CTest)
+	}
+}
+
+// TestResolveBuildToolsRejectsOldCMake verifies that a cmake older than the
+// version lib/CMakeLists.txt requires is reported up front, as is a cmake
+// that cannot be found at all.
+func TestResolveBuildToolsRejectsOldCMake(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	t.Setenv("PATH", filepath.Join(root, "bin"))
+
+	if _, err := resolveBuildTools(map[string]string{}, BuildScopeLibs, nil); err == nil || !strings.Contains(err.Error(), "cmake was not found") {
+		t.Fatalf("expected a missing cmake to be reported, got %v", err)
+	}
+
+	writeTestTool(t, filepath.Join(root, "bin", "cmake"), "cmake version 3.10.2")
+	_, err := resolveBuildTools(map[string]string{}, BuildScopeLibs, nil)
+	if err == nil || !strings.Contains(err.Error(), "3.10.2 is too old; version 3.16 or newer is required") {
+		t.Fatalf("expected an old cmake to be rejected, got %v", err)
+	}
+}
+
+// TestCheckCompilersRejectsOldCompilers verifies that a compiler older than
+// the target's Qt version supports is reported up front, and that a compiler
+// shared by Qt 5 and Qt 6 targets must satisfy the Qt 6 minimum.
+func TestCheckCompilersRejectsOldCompilers(t *testing.T) {
+	root := t.TempDir()
+	writeTestTool(t, filepath.Join(root, "g++"), "8.3.0")
+	envConfig := map[string]string{"DESKTOP_CXX_COMPILER": filepath.Join(root, "g++")}
+	qt5 := BuildTarget{OS: "desktop", QtVersion: "qt5", Env: TargetEnv{CXXCompiler: "DESKTOP_CXX_COMPILER"}}
+	qt6 := BuildTarget{OS: "desktop", QtVersion: "qt6", Env: TargetEnv{CXXCompiler: "DESKTOP_CXX_COMPILER"}}
+
+	compilers, err := checkCompilers(envConfig, []BuildTarget{qt5})
+	if err != nil {
+		t.Fatalf("expected GCC 8 to be accepted for Qt 5, got error: %v", err)
+	}
+	if len(compilers) != 1 || compilers[0].Version != "8.3.0" {
+		t.Fatalf("expected the compiler version to be recorded, got %+v", compilers)
+	}
+
+	_, err = checkCompilers(envConfig, []BuildTarget{qt5, qt6})
+	if err == nil || !strings.Contains(err.Error(), "8.3.0 is too old; version 9 or newer is required") {
+		t.Fatalf("expected GCC 8 to be rejected for Qt 6, got %v", err)
+	}
+}
+
+// testBuildTools returns the tools of a typical Linux host with cmake and
+// ninja installed in /usr/bin.
+func testBuildTools() buildTools {
+	return buildTools{
+		CMake:     buildTool{Name: "cmake", Path: "/usr/bin/cmake", Version: "3.28.3"},
+		CTest:     buildTool{Name: "ctest", Path: "/usr/bin/ctest", Version: "3.28.3"},
+		Generator: "Ninja",
+		Make:      buildTool{Name: "ninja", Path: "/usr/bin/ninja", Version: "1.11.1"},
+	}
+}
+
+// testDesktopTargets returns a desktop target that reads its compiler from
+// DESKTOP_CXX_COMPILER.
+func testDesktopTargets() []BuildTarget {
+	return []BuildTarget{{OS: "desktop", Env: TargetEnv{CXXCompiler: "DESKTOP_CXX_COMPILER"}}}
+}
+
+// writeTestTool writes an executable script that prints output for any
+// arguments, standing in for --version and -dumpversion.
+func writeTestTool(t *testing.T, path, output string) {
+	t.Helper()
+
+	writeTestFile(t, path, "#!/bin/sh\necho '"+output+"'\n")
+	if err := os.Chmod(path, 0755); err != nil {
+		t.Fatalf("failed to make %s executable: %v", path, err)
+	}
+}
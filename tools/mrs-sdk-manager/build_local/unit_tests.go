@@ -0,0 +1,240 @@
+package buildlocal
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mrs-sdk-manager/utils"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// testReportFileName is the JUnit report written by CTest inside each test
+// build directory. The combined report of a run uses the same name in
+// build/tests/.
+const testReportFileName = "junit.xml"
+
+// runTests builds the SDK unit tests for the selected targets and runs them
+// with CTest on the targets that can execute them. The results are printed
+// per target and combined into a single JUnit report.
+func runTests(ctx context.Context, sdkRoot string, envConfig map[string]string, tools buildTools, targets []BuildTarget, opts Options) error {
+	utils.PrintTaskStart("Building and running MRS SDK unit tests...")
+	configs := getTestBuildConfigs(sdkRoot, envConfig, tools, targets)
+
+	// Results of an earlier run must not be mistaken for this one.
+	for _, config := range configs {
+		if err := os.Remove(filepath.Join(config.BuildDir, testReportFileName)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove old test report: %w", err)
+		}
+	}
+
+	concurrency, jobsPerTarget := jobBudget(opts.Jobs, opts.ParallelTargets, len(configs))
+	color.White("Building up to %d target(s) at a time with %d job(s) each", concurrency, jobsPerTarget)
+
+	reporter := newProgressReporter(opts.Output, os.Stdout)
+	buildErr := runAllBuilds(ctx, sdkRoot, configs, concurrency, jobsPerTarget, opts.KeepGoing, reporter)
+
+	reportPath := filepath.Join(sdkRoot, "build", "tests", testReportFileName)
+	summaries, err := collectTestResults(configs, reportPath)
+	if err != nil {
+		return err
+	}
+	printTestSummaries(color.Output, summaries)
+	color.White("JUnit report: %s", reportPath)
+
+	if buildErr != nil {
+		return buildErr
+	}
+	utils.PrintSuccess("All unit tests passed")
+	return nil
+}
+
+// getTestBuildConfigs returns configurations that build the library with its
+// unit tests in build/tests/<target>. Desktop targets run the tests with
+// CTest. ARM targets only compile them, unless a test runner such as
+// qemu-user is configured for their architecture.
+func getTestBuildConfigs(sdkRoot string, envConfig map[string]string, tools buildTools, targets []BuildTarget) []BuildConfig {
+	var configs []BuildConfig
+	for _, target := range targets {
+		buildDir := filepath.Join(sdkRoot, "build", "tests", target.BuildDir())
+		config := newLibBuildConfig(sdkRoot, envConfig, tools, target, buildDir)
+		config.Project = "tests"
+		config.ConfigureCmd = append(config.ConfigureCmd, "-DMRS_SDK_QT_BUILD_TESTS:BOOL=ON")
+		// tst_buildinfo compares what the library reports with the target
+		// matrix, rather than with the defines it was built from.
+		config.ConfigureCmd = append(config.ConfigureCmd,
+			"-DMRS_SDK_QT_TEST_EXPECTED_DEVICE:STRING="+target.Device,
+			"-DMRS_SDK_QT_TEST_EXPECTED_OS:STRING="+target.OS)
+
+		emulator, canRun := testEmulator(envConfig, target)
+		if len(emulator) > 0 {
+			// CTest prefixes every test command with the emulator.
+			config.ConfigureCmd = append(config.ConfigureCmd, "-DCMAKE_CROSSCOMPILING_EMULATOR:STRING="+strings.Join(emulator, ";"))
+		}
+		if canRun {
+			config.TestCmd = []string{
+				tools.CTest.Path,
+				"--test-dir", buildDir,
+				"--output-on-failure",
+				"--no-tests=error",
+				"--output-junit", filepath.Join(buildDir, testReportFileName),
+			}
+		}
+
+		configs = append(configs, config)
+	}
+
+	return configs
+}
+
+// testEmulator reports whether the tests of a target can be run on this
+// machine and, for cross-compiled targets, returns the emulator command that
+// runs them against the target's sysroot.
+func testEmulator(envConfig map[string]string, target BuildTarget) ([]string, bool) {
+	if target.OS == "desktop" {
+		return nil, true
+	}
+
+	runner := envConfig[target.Env.TestRunner]
+	if target.Env.TestRunner == "" || runner == "" {
+		return nil, false
+	}
+
+	return []string{runner, "-L", envConfig[target.Env.Sysroot]}, true
+}
+
+// junitTestSuites is the root of the combined JUnit report.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite is the subset of a CTest JUnit report that is carried over
+// into the combined report.
+type junitTestSuite struct {
+	XMLName xml.Name        `xml:"testsuite"`
+	Name    string          `xml:"name,attr"`
+	Tests   int             `xml:"tests,attr"`
+	Failed  int             `xml:"failures,attr"`
+	Skipped int             `xml:"skipped,attr"`
+	Time    string          `xml:"time,attr,omitempty"`
+	Cases   []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr,omitempty"`
+	Status    string        `xml:"status,attr,omitempty"`
+	Failure   *junitMessage `xml:"failure"`
+	Skipped   *junitMessage `xml:"skipped"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// testSummary is the outcome of the unit tests of one target.
+type testSummary struct {
+	config  BuildConfig
+	ran     bool // A CTest report was produced
+	passed  int
+	failed  int
+	skipped int
+}
+
+// collectTestResults reads the CTest report of every configuration and
+// writes the combined JUnit report to reportPath, with one test suite per
+// target.
+func collectTestResults(configs []BuildConfig, reportPath string) ([]testSummary, error) {
+	var combined junitTestSuites
+	var summaries []testSummary
+	for _, config := range configs {
+		summary := testSummary{config: config}
+
+		data, err := os.ReadFile(filepath.Join(config.BuildDir, testReportFileName))
+		if err == nil {
+			var suite junitTestSuite
+			if err := xml.Unmarshal(data, &suite); err != nil {
+				return nil, fmt.Errorf("failed to parse test report of %s: %w", config.Target.BuildDir(), err)
+			}
+
+			summary.ran = true
+			suite.Name = config.Target.BuildDir()
+			suite.Failed, suite.Skipped = 0, 0
+			for _, testCase := range suite.Cases {
+				switch {
+				case testCase.Failure != nil || testCase.Status == "fail":
+					suite.Failed++
+				case testCase.Skipped != nil || testCase.Status == "notrun" || testCase.Status == "disabled":
+					suite.Skipped++
+				}
+			}
+			suite.Tests = len(suite.Cases)
+			summary.failed, summary.skipped = suite.Failed, suite.Skipped
+			summary.passed = suite.Tests - suite.Failed - suite.Skipped
+
+			combined.Suites = append(combined.Suites, suite)
+			combined.Tests += suite.Tests
+			combined.Failures += suite.Failed
+			combined.Skipped += suite.Skipped
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read test report of %s: %w", config.Target.BuildDir(), err)
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	data, err := xml.MarshalIndent(combined, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode test report: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(reportPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create test report directory: %w", err)
+	}
+	if err := os.WriteFile(reportPath, append([]byte(xml.Header), append(data, '\n')...), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write test report: %w", err)
+	}
+
+	return summaries, nil
+}
+
+// printTestSummaries prints one line per target with its test counts, or why
+// the tests were not run.
+func printTestSummaries(out io.Writer, summaries []testSummary) {
+	maxLen := 0
+	for _, summary := range summaries {
+		maxLen = max(maxLen, len(summary.config.Target.BuildDir()))
+	}
+
+	fmt.Fprintln(out, "Unit test results:")
+	for _, summary := range summaries {
+		name := summary.config.Target.BuildDir()
+		padding := strings.Repeat(" ", maxLen-len(name)+3)
+
+		var result string
+		switch {
+		case summary.ran && summary.failed > 0:
+			result = color.RedString("%d passed, %d failed, %d skipped", summary.passed, summary.failed, summary.skipped)
+		case summary.ran:
+			result = color.GreenString("%d passed, %d failed, %d skipped", summary.passed, summary.failed, summary.skipped)
+		case len(summary.config.TestCmd) == 0 && summary.config.Target.Env.TestRunner == "":
+			result = color.YellowString("compiled only (no test runner in the build matrix)")
+		case len(summary.config.TestCmd) == 0:
+			result = color.YellowString("compiled only (set %s to run)", summary.config.Target.Env.TestRunner)
+		default:
+			result = color.RedString("no results (see %s)", summary.config.logPath())
+		}
+		fmt.Fprintf(out, "  %s%s%s\n", name, padding, result)
+	}
+}
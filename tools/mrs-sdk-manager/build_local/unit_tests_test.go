@@ -0,0 +1,127 @@
+package buildlocal
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestGetTestBuildConfigsRunsOnlyRunnableTargets verifies that desktop tests
+// are run with CTest, while ARM tests are only compiled unless an emulator is
+// configured for their architecture.
+func TestGetTestBuildConfigsRunsOnlyRunnableTargets(t *testing.T) {
+	t.Setenv("MRS_SDK_QT_ROOT", "/tmp/mrs-sdk-root")
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{Targets: []string{"desktop-desktop-qt6", "mconn-yocto-qt5", "neuralplex-yocto-qt6"}})
+	if err != nil {
+		t.Fatalf("expected selection to succeed, got error: %v", err)
+	}
+
+	envConfig := testEnvConfig()
+	envConfig["ARM_TEST_RUNNER"] = "/usr/bin/qemu-arm"
+
+	byName := map[string]BuildConfig{}
+	for _, config := range getTestBuildConfigs("/tmp/mrs-sdk-qt", envConfig, testBuildTools(), targets) {
+		byName[config.Target.Name()] = config
+		if !slices.Contains(config.ConfigureCmd, "-DMRS_SDK_QT_BUILD_TESTS:BOOL=ON") {
+			t.Fatalf("expected tests to be enabled for %s, got %v", config.label(), config.ConfigureCmd)
+		}
+		if config.BuildDir != filepath.Join("/tmp/mrs-sdk-qt/build/tests", config.Target.BuildDir()) {
+			t.Fatalf("expected a separate test build dir, got %q", config.BuildDir)
+		}
+	}
+
+	mconnExpected := []string{"-DMRS_SDK_QT_TEST_EXPECTED_DEVICE:STRING=mconn", "-DMRS_SDK_QT_TEST_EXPECTED_OS:STRING=yocto"}
+	for _, arg := range mconnExpected {
+		if !slices.Contains(byName["mconn-yocto-qt5"].ConfigureCmd, arg) {
+			t.Fatalf("expected %s for MConn tests, got %v", arg, byName["mconn-yocto-qt5"].ConfigureCmd)
+		}
+	}
+
+	desktop := byName["desktop-desktop-qt6"]
+	if len(desktop.TestCmd) == 0 || desktop.TestCmd[0] != "/usr/bin/ctest" {
+		t.Fatalf("expected desktop tests to run with ctest, got %v", desktop.TestCmd)
+	}
+	if !slices.Contains(desktop.TestCmd, filepath.Join(desktop.BuildDir, testReportFileName)) {
+		t.Fatalf("expected ctest to write a JUnit report, got %v", desktop.TestCmd)
+	}
+
+	mconn := byName["mconn-yocto-qt5"]
+	if len(mconn.TestCmd) == 0 || !slices.Contains(mconn.ConfigureCmd, "-DCMAKE_CROSSCOMPILING_EMULATOR:STRING=/usr/bin/qemu-arm;-L;/tmp/yocto/sysroot") {
+		t.Fatalf("expected MConn tests to run through qemu-arm, got configure %v and test %v", mconn.ConfigureCmd, mconn.TestCmd)
+	}
+
+	neuralplex := byName["neuralplex-yocto-qt6"]
+	if len(neuralplex.TestCmd) != 0 {
+		t.Fatalf("expected NeuralPlex tests to only compile without AARCH64_TEST_RUNNER, got %v", neuralplex.TestCmd)
+	}
+}
+
+// TestCollectTestResultsCombinesReports verifies that the CTest reports of all
+// targets are merged into one JUnit file with a suite per target, and that
+// targets without a report are summarized as not run.
+func TestCollectTestResultsCombinesReports(t *testing.T) {
+	sdkRoot := t.TempDir()
+	targets, err := testTargetMatrix(t).SelectBuildTargets([]string{"debug"}, TargetFilter{Targets: []string{"desktop-desktop-qt5", "desktop-desktop-qt6", "mconn-yocto-qt5"}})
+	if err != nil {
+		t.Fatalf("expected selection to succeed, got error: %v", err)
+	}
+	// Selected targets follow the matrix order: MConn, then the desktop kits.
+	configs := getTestBuildConfigs(sdkRoot, testEnvConfig(), testBuildTools(), targets)
+
+	writeTestFile(t, filepath.Join(configs[1].BuildDir, testReportFileName), `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="host" tests="2" failures="1" disabled="0" skipped="0" hostname="host" time="1" timestamp="2026-01-01T00:00:00">
+	<testcase name="tst_buildinfo" classname="tst_buildinfo" time="0.1" status="run">
+		<system-out>PASS</system-out>
+	</testcase>
+	<testcase name="tst_other" classname="tst_other" time="0.2" status="fail">
+		<failure message="Failed"/>
+	</testcase>
+</testsuite>
+`)
+	writeTestFile(t, filepath.Join(configs[2].BuildDir, testReportFileName), `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="host" tests="1" failures="0" disabled="0" skipped="0" time="1">
+	<testcase name="tst_buildinfo" classname="tst_buildinfo" time="0.1" status="run"/>
+</testsuite>
+`)
+
+	reportPath := filepath.Join(sdkRoot, "build", "tests", testReportFileName)
+	summaries, err := collectTestResults(configs, reportPath)
+	if err != nil {
+		t.Fatalf("expected results to be collected, got error: %v", err)
+	}
+
+	if summaries[0].ran {
+		t.Fatalf("expected the MConn tests to be reported as not run, got %+v", summaries[0])
+	}
+	if !summaries[1].ran || summaries[1].passed != 1 || summaries[1].failed != 1 {
+		t.Fatalf("unexpected qt5 summary %+v", summaries[1])
+	}
+	if !summaries[2].ran || summaries[2].passed != 1 || summaries[2].failed != 0 {
+		t.Fatalf("unexpected qt6 summary %+v", summaries[2])
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("expected a combined report: %v", err)
+	}
+	var combined junitTestSuites
+	if err := xml.Unmarshal(data, &combined); err != nil {
+		t.Fatalf("expected the combined report to be valid XML: %v", err)
+	}
+	if combined.Tests != 3 || combined.Failures != 1 || len(combined.Suites) != 2 {
+		t.Fatalf("unexpected combined totals %+v", combined)
+	}
+	if combined.Suites[0].Name != "desktop-desktop-qt5-debug" || combined.Suites[1].Name != "desktop-desktop-qt6-debug" {
+		t.Fatalf("expected suites to be named after their targets, got %q and %q", combined.Suites[0].Name, combined.Suites[1].Name)
+	}
+
+	var out bytes.Buffer
+	printTestSummaries(&out, summaries)
+	if !strings.Contains(out.String(), "compiled only (set ARM_TEST_RUNNER to run)") {
+		t.Fatalf("expected the summary to explain why MConn tests did not run, got:\n%s", out.String())
+	}
+}
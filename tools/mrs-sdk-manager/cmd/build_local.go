@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	buildLocal "mrs-sdk-manager/build_local"
 
 	"github.com/spf13/cobra"
@@ -9,9 +10,14 @@ import (
 var buildLocalCmd = &cobra.Command{
 	Use:   "build-local [TARGET]",
 	Short: "Build SDK libraries and/or demo projects from source",
-	Long:  "Build SDK libraries and/or demo projects from source. TARGET may be one of: all, libs, demos. Defaults to all.",
+	Long:  "Build SDK libraries and/or demo projects from source. TARGET may be one of: all, libs, demos, test. Defaults to all. Demos are built for every selected target against the installed SDK version. The test target builds the SDK unit tests and runs them with ctest where possible.",
 	Args:  cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		sdkRoot, err := repoRoot(cmd)
+		if err != nil {
+			return err
+		}
+
 		targetArg := ""
 		if len(args) == 1 {
 			targetArg = args[0]
@@ -27,11 +33,93 @@ var buildLocalCmd = &cobra.Command{
 			return err
 		}
 
-		return buildLocal.Run(scope, installFlag)
+		rawBuildTypes, err := cmd.Flags().GetStringSlice("build-type")
+		if err != nil {
+			return err
+		}
+
+		buildTypes, err := buildLocal.ParseBuildTypes(rawBuildTypes)
+		if err != nil {
+			return err
+		}
+
+		var filter buildLocal.TargetFilter
+		if filter.Devices, err = cmd.Flags().GetStringSlice("device"); err != nil {
+			return err
+		}
+		if filter.OSes, err = cmd.Flags().GetStringSlice("os"); err != nil {
+			return err
+		}
+		if filter.QtVersions, err = cmd.Flags().GetStringSlice("qt"); err != nil {
+			return err
+		}
+		if filter.Targets, err = cmd.Flags().GetStringSlice("target"); err != nil {
+			return err
+		}
+
+		forceFlag, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return err
+		}
+
+		keepGoingFlag, err := cmd.Flags().GetBool("keep-going")
+		if err != nil {
+			return err
+		}
+
+		dryRunFlag, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return err
+		}
+
+		jobs, err := cmd.Flags().GetInt("jobs")
+		if err != nil {
+			return err
+		}
+		parallelTargets, err := cmd.Flags().GetInt("parallel-targets")
+		if err != nil {
+			return err
+		}
+		if jobs < 0 || parallelTargets < 0 {
+			return fmt.Errorf("--jobs and --parallel-targets must not be negative")
+		}
+
+		rawOutput, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		output, err := buildLocal.ParseOutputMode(rawOutput)
+		if err != nil {
+			return err
+		}
+
+		return buildLocal.Run(sdkRoot, scope, buildLocal.Options{
+			Install:         installFlag,
+			BuildTypes:      buildTypes,
+			Filter:          filter,
+			Force:           forceFlag,
+			KeepGoing:       keepGoingFlag,
+			DryRun:          dryRunFlag,
+			Jobs:            jobs,
+			ParallelTargets: parallelTargets,
+			Output:          output,
+		})
 	},
 }
 
 func init() {
 	buildLocalCmd.Flags().BoolP("install", "i", false, "Install compiled libraries to $MRS_SDK_QT_ROOT")
+	buildLocalCmd.Flags().BoolP("force", "f", false, "Rebuild targets even if they are up to date")
+	buildLocalCmd.Flags().BoolP("keep-going", "k", false, "Keep building other targets after a failure and report every failure at the end")
+	buildLocalCmd.Flags().IntP("jobs", "j", 0, "Total compile jobs shared by all targets (default: number of CPUs)")
+	buildLocalCmd.Flags().Bool("dry-run", false, "Print the commands, env config values and installed files without building or installing anything")
+	buildLocalCmd.Flags().Int("parallel-targets", 0, "Number of targets to build at the same time (default: jobs/4)")
+	buildLocalCmd.Flags().StringP("output", "o", string(buildLocal.OutputAuto), "Progress output: auto, table, plain or json")
+	buildLocalCmd.Flags().StringSlice("build-type", []string{"debug"}, "Comma-separated build types to compile: debug, release, relwithdebinfo")
+	buildLocalCmd.Flags().StringSlice("device", nil, "Only build targets for these devices (e.g. mconn,fusion,neuralplex,desktop)")
+	buildLocalCmd.Flags().StringSlice("os", nil, "Only build targets for these operating systems (e.g. yocto,buildroot,desktop)")
+	buildLocalCmd.Flags().StringSlice("qt", nil, "Only build targets for these Qt versions (e.g. qt5,qt6)")
+	buildLocalCmd.Flags().StringSlice("target", nil, "Only build these targets, by build directory name (e.g. mconn-yocto-qt5)")
+	addRepoFlag(buildLocalCmd)
 	rootCmd.AddCommand(buildLocalCmd)
 }
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	buildLocal "mrs-sdk-manager/build_local"
+
+	"github.com/spf13/cobra"
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean [target...]",
+	Short: "Remove build-local build directories",
+	Long:  "Remove the build directories of build-local targets, including their test and demo builds, and print the disk space reclaimed. A target may be given as a build directory, e.g. mconn-yocto-qt5-debug, or as a target name to clean all of its build types. Nothing outside the repository's build directory is removed.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sdkRoot, err := repoRoot(cmd)
+		if err != nil {
+			return err
+		}
+
+		allFlag, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			return err
+		}
+
+		cacheOnlyFlag, err := cmd.Flags().GetBool("cache-only")
+		if err != nil {
+			return err
+		}
+
+		return buildLocal.Clean(sdkRoot, args, allFlag, cacheOnlyFlag)
+	},
+}
+
+func init() {
+	cleanCmd.Flags().Bool("all", false, "Clean every target, removing the whole build directory")
+	cleanCmd.Flags().Bool("cache-only", false, "Only remove CMakeCache.txt and generated CMake files, so the next build configures from scratch")
+	addRepoFlag(cleanCmd)
+	rootCmd.AddCommand(cleanCmd)
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	buildLocal "mrs-sdk-manager/build_local"
+
+	"github.com/spf13/cobra"
+)
+
+var compileCommandsCmd = &cobra.Command{
+	Use:   "compile-commands [target...]",
+	Short: "Write compile_commands.json for clangd from build-local targets",
+	Long:  "Write compile_commands.json to the repository root from the compilation databases exported by build-local, e.g. for mconn-yocto-qt5-debug. The build type suffix may be omitted when only one build type was built. Cross-compiler commands are rewritten with --target and --sysroot flags so that clangd indexes the code of the selected device. When several targets are given, their databases are merged and the first target wins for files that more than one of them compiles. Without a target, the most recently built target is used.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sdkRoot, err := repoRoot(cmd)
+		if err != nil {
+			return err
+		}
+
+		return buildLocal.WriteCompileCommands(sdkRoot, args)
+	},
+}
+
+func init() {
+	addRepoFlag(compileCommandsCmd)
+	rootCmd.AddCommand(compileCommandsCmd)
+}
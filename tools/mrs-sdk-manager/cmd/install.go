@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+
+	buildLocal "mrs-sdk-manager/build_local"
+	"mrs-sdk-manager/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var installCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install SDK libraries that build-local already built",
+	Long:  "Install the SDK libraries from build/<target>/artifacts, together with the headers and CMake/QMake helpers, without building anything. The artifacts must have been built from the checked out commit and must be up to date with the sources under lib/ and the env config. By default every built target is installed under the version of the checkout. The installation it replaces is kept, and --rollback restores it.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		version, err := cmd.Flags().GetString("version")
+		if err != nil {
+			return err
+		}
+
+		targets, err := cmd.Flags().GetStringSlice("target")
+		if err != nil {
+			return err
+		}
+
+		rollbackFlag, err := cmd.Flags().GetBool("rollback")
+		if err != nil {
+			return err
+		}
+		if rollbackFlag && len(targets) > 0 {
+			return fmt.Errorf("--target cannot be combined with --rollback, which restores the whole version")
+		}
+
+		// Rolling back a given version works on the SDK installation only and
+		// does not need a checkout.
+		if rollbackFlag && version != "" {
+			return buildLocal.RollbackInstall(version)
+		}
+
+		sdkRoot, err := repoRoot(cmd)
+		if err != nil {
+			return err
+		}
+		if rollbackFlag {
+			return buildLocal.RollbackInstall(utils.ResolveSDKVersion(sdkRoot))
+		}
+
+		return buildLocal.Install(sdkRoot, buildLocal.InstallOptions{
+			Version: version,
+			Targets: targets,
+		})
+	},
+}
+
+func init() {
+	installCmd.Flags().String("version", "", "SDK version to install as (default: the latest Git tag of the checkout)")
+	installCmd.Flags().StringSlice("target", nil, "Only install these targets, by build directory or target name (e.g. mconn-yocto-qt5-debug,desktop-desktop-qt6)")
+	installCmd.Flags().Bool("rollback", false, "Restore the installation that the last install of the version replaced, instead of installing")
+	addRepoFlag(installCmd)
+	rootCmd.AddCommand(installCmd)
+}
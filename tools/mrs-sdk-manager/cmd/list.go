@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"mrs-sdk-manager/manifest"
+
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List installed SDK versions",
+	Long:  "List the SDK versions installed in MRS_SDK_QT_ROOT with their library targets and build types, whether demos are installed, when and from which commit they were installed, and whether the project in the working directory pins them.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonFlag, err := cmd.Flags().GetBool("json")
+		if err != nil {
+			return err
+		}
+
+		return manifest.List(jsonFlag)
+	},
+}
+
+func init() {
+	listCmd.Flags().Bool("json", false, "Print the installed versions as JSON")
+	rootCmd.AddCommand(listCmd)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	buildLocal "mrs-sdk-manager/build_local"
+
+	"github.com/spf13/cobra"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [target]",
+	Short: "Show the build log of a build-local target",
+	Long:  "Show the build log written by build-local for a target, e.g. mconn-yocto-qt5-debug. The build type suffix may be omitted when only one build type has a log. Without a target, the most recently written log is shown. --demo and --tests select the logs of a demo build or of the unit test build instead of the library build.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sdkRoot, err := repoRoot(cmd)
+		if err != nil {
+			return err
+		}
+
+		targetArg := ""
+		if len(args) == 1 {
+			targetArg = args[0]
+		}
+
+		followFlag, err := cmd.Flags().GetBool("follow")
+		if err != nil {
+			return err
+		}
+
+		errorsFlag, err := cmd.Flags().GetBool("errors")
+		if err != nil {
+			return err
+		}
+
+		demoFlag, err := cmd.Flags().GetString("demo")
+		if err != nil {
+			return err
+		}
+
+		testsFlag, err := cmd.Flags().GetBool("tests")
+		if err != nil {
+			return err
+		}
+
+		return buildLocal.ShowLogs(sdkRoot, targetArg, demoFlag, testsFlag, followFlag, errorsFlag)
+	},
+}
+
+func init() {
+	logsCmd.Flags().BoolP("follow", "f", false, "Keep printing new output as it is written")
+	logsCmd.Flags().BoolP("errors", "e", false, "Only show compiler and CMake error lines")
+	logsCmd.Flags().String("demo", "", "Show the log of a demo build, e.g. can-simulator")
+	logsCmd.Flags().Bool("tests", false, "Show the log of the unit test build")
+	logsCmd.MarkFlagsMutuallyExclusive("demo", "tests")
+	addRepoFlag(logsCmd)
+	rootCmd.AddCommand(logsCmd)
+}
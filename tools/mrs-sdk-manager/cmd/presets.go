@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	buildLocal "mrs-sdk-manager/build_local"
+
+	"github.com/spf13/cobra"
+)
+
+var presetsCmd = &cobra.Command{
+	Use:   "presets",
+	Short: "Generate CMake presets for the SDK library",
+	Long:  "Write lib/CMakePresets.json and lib/CMakeUserPresets.json with a configure and build preset for every target and build type, using the same settings as build-local. Paths from the env config are only written to CMakeUserPresets.json, so CMakePresets.json can be committed. Both files are overwritten.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sdkRoot, err := repoRoot(cmd)
+		if err != nil {
+			return err
+		}
+
+		return buildLocal.WritePresets(sdkRoot)
+	},
+}
+
+func init() {
+	addRepoFlag(presetsCmd)
+	rootCmd.AddCommand(presetsCmd)
+}
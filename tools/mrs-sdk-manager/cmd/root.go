@@ -3,6 +3,8 @@ package cmd
 import (
 	"os"
 
+	buildLocal "mrs-sdk-manager/build_local"
+
 	"github.com/spf13/cobra"
 )
 
@@ -18,3 +20,20 @@ func Execute() {
 		os.Exit(1)
 	}
 }
+
+// addRepoFlag adds the --repo flag to a command that works on an mrs-sdk-qt
+// checkout.
+func addRepoFlag(cmd *cobra.Command) {
+	cmd.Flags().String("repo", "", "Path of the mrs-sdk-qt checkout (default: the checkout containing the working directory)")
+}
+
+// repoRoot returns the checkout selected by --repo, or the one containing the
+// working directory.
+func repoRoot(cmd *cobra.Command) (string, error) {
+	repoFlag, err := cmd.Flags().GetString("repo")
+	if err != nil {
+		return "", err
+	}
+
+	return buildLocal.FindRepoRoot(repoFlag)
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"mrs-sdk-manager/manifest"
+
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [sdk-version]",
+	Short: "Check installed SDK versions against their manifest",
+	Long:  "Compare the files of an installed SDK version with the manifest.json written when it was installed, and report missing, modified and unexpected files. Without a version, every version in MRS_SDK_QT_ROOT is checked.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sdkVersion := ""
+		if len(args) == 1 {
+			sdkVersion = args[0]
+		}
+
+		return manifest.Verify(sdkVersion)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
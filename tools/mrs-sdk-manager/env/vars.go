@@ -30,6 +30,21 @@ var (
 		Description: "Path to the Yocto Qt5 environment setup script",
 		Type:        FilePath,
 	}
+	YOCTO_QT6_SYSROOT = EnvVar{
+		Key:         "YOCTO_QT6_SYSROOT",
+		Description: "Path to the Yocto Qt6 sysroot",
+		Type:        DirPath,
+	}
+	YOCTO_QT6_CXX_COMPILER = EnvVar{
+		Key:         "YOCTO_QT6_CXX_COMPILER",
+		Description: "Path to the Yocto Qt6 C++ cross-compiler",
+		Type:        FilePath,
+	}
+	YOCTO_QT6_ENV_SETUP_SCRIPT = EnvVar{
+		Key:         "YOCTO_QT6_ENV_SETUP_SCRIPT",
+		Description: "Path to the Yocto Qt6 environment setup script",
+		Type:        FilePath,
+	}
 	BUILDROOT_QT5_SYSROOT = EnvVar{
 		Key:         "BUILDROOT_QT5_SYSROOT",
 		Description: "Path to the Buildroot Qt5 sysroot",
@@ -55,16 +70,43 @@ var (
 		Description: "Path to the desktop Qt6 installation",
 		Type:        DirPath,
 	}
+	ARM_TEST_RUNNER = EnvVar{
+		Key:         "ARM_TEST_RUNNER",
+		Description: "Optional path to an emulator (e.g. qemu-arm) that runs 32-bit ARM unit tests",
+		Type:        FilePath,
+	}
+	AARCH64_TEST_RUNNER = EnvVar{
+		Key:         "AARCH64_TEST_RUNNER",
+		Description: "Optional path to an emulator (e.g. qemu-aarch64) that runs 64-bit ARM unit tests",
+		Type:        FilePath,
+	}
+	CMAKE_PROGRAM = EnvVar{
+		Key:         "CMAKE_PROGRAM",
+		Description: "Optional path to cmake; defaults to the PATH and the Qt Tools directories",
+		Type:        FilePath,
+	}
+	NINJA_PROGRAM = EnvVar{
+		Key:         "NINJA_PROGRAM",
+		Description: "Optional path to ninja; defaults to the PATH and the Qt Tools directories",
+		Type:        FilePath,
+	}
 )
 var allVars = []EnvVar{
 	YOCTO_QT5_SYSROOT,
 	YOCTO_QT5_CXX_COMPILER,
 	YOCTO_QT5_ENV_SETUP_SCRIPT,
+	YOCTO_QT6_SYSROOT,
+	YOCTO_QT6_CXX_COMPILER,
+	YOCTO_QT6_ENV_SETUP_SCRIPT,
 	BUILDROOT_QT5_SYSROOT,
 	BUILDROOT_QT5_CXX_COMPILER,
 	DESKTOP_CXX_COMPILER,
 	DESKTOP_QT5_PREFIX,
 	DESKTOP_QT6_PREFIX,
+	ARM_TEST_RUNNER,
+	AARCH64_TEST_RUNNER,
+	CMAKE_PROGRAM,
+	NINJA_PROGRAM,
 }
 
 // These are different data representations of the env variables.
@@ -4,11 +4,13 @@ go 1.24.0
 
 require github.com/spf13/cobra v1.10.2
 
+require gopkg.in/yaml.v3 v3.0.1
+
 require (
 	github.com/fatih/color v1.18.0
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.14 // indirect
-	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/mattn/go-isatty v0.0.20
 	github.com/spf13/pflag v1.0.10 // indirect
 	golang.org/x/sys v0.39.0 // indirect
 )
@@ -0,0 +1,199 @@
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mrs-sdk-manager/use"
+	"mrs-sdk-manager/utils"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// libraryFileName is the static library installed for every target and
+// build type.
+const libraryFileName = "libmrs-sdk-qt.a"
+
+// InstalledVersion describes an SDK version in MRS_SDK_QT_ROOT.
+type InstalledVersion struct {
+	Version     string             `json:"version"`
+	InstalledAt time.Time          `json:"installed_at"` // From the manifest, else the directory's modification time
+	Commit      string             `json:"commit"`       // Empty without a manifest
+	Demos       bool               `json:"demos"`
+	Pinned      bool               `json:"pinned"` // Pinned by the project in the working directory
+	Libraries   []InstalledLibrary `json:"libraries"`
+}
+
+// InstalledLibrary is a target's library tree (BuildTarget.InstTreeDir) in an
+// installed SDK version, with the build types it has a library for.
+type InstalledLibrary struct {
+	Dir        string   `json:"dir"`    // Slash-separated, e.g. "lib/qt5/yocto/linux_arm_mconn"
+	Target     string   `json:"target"` // Target name from the manifest; empty if unknown
+	BuildTypes []string `json:"build_types"`
+}
+
+// List prints the SDK versions installed in MRS_SDK_QT_ROOT, or writes them
+// as JSON.
+func List(jsonOutput bool) error {
+	sdkInstallRoot, err := utils.ResolveSDKInstallRoot()
+	if err != nil {
+		return err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	pinned, err := use.PinnedVersion(cwd)
+	if err != nil {
+		return fmt.Errorf("failed to read the pinned SDK version: %w", err)
+	}
+
+	versions, err := listInstalledVersions(sdkInstallRoot, pinned)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		if versions == nil {
+			versions = []InstalledVersion{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(versions)
+	}
+
+	if len(versions) == 0 {
+		color.White("No SDK versions are installed in %s", sdkInstallRoot)
+		return nil
+	}
+	printInstalledVersions(color.Output, versions)
+	return nil
+}
+
+// listInstalledVersions describes every SDK version in sdkInstallRoot.
+func listInstalledVersions(sdkInstallRoot, pinned string) ([]InstalledVersion, error) {
+	names, err := InstalledVersions(sdkInstallRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []InstalledVersion
+	for _, name := range names {
+		version, err := describeVersion(filepath.Join(sdkInstallRoot, name), name)
+		if err != nil {
+			return nil, err
+		}
+		version.Pinned = name == pinned
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func describeVersion(versionRoot, name string) (InstalledVersion, error) {
+	version := InstalledVersion{Version: name, Libraries: []InstalledLibrary{}}
+
+	info, err := os.Stat(versionRoot)
+	if err != nil {
+		return version, err
+	}
+	version.InstalledAt = info.ModTime()
+
+	targetNames := map[string]string{}
+	manifest, err := Read(versionRoot)
+	switch {
+	case err == nil:
+		version.InstalledAt = manifest.InstalledAt
+		version.Commit = manifest.Commit
+		for _, target := range manifest.Targets {
+			targetNames[path.Dir(target.LibDir)] = target.Name
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return version, err
+	}
+
+	if info, err := os.Stat(filepath.Join(versionRoot, "demos")); err == nil && info.IsDir() {
+		version.Demos = true
+	}
+
+	// Libraries are installed to lib/<qt>/<os>/<system>_<processor>_<device>/<build type>.
+	libs, err := filepath.Glob(filepath.Join(versionRoot, "lib", "*", "*", "*", "*", libraryFileName))
+	if err != nil {
+		return version, err
+	}
+	for _, lib := range libs {
+		buildTypeDir := filepath.Dir(lib)
+		treeDir, err := filepath.Rel(versionRoot, filepath.Dir(buildTypeDir))
+		if err != nil {
+			return version, err
+		}
+		treeDir = filepath.ToSlash(treeDir)
+
+		index := slices.IndexFunc(version.Libraries, func(library InstalledLibrary) bool { return library.Dir == treeDir })
+		if index < 0 {
+			version.Libraries = append(version.Libraries, InstalledLibrary{Dir: treeDir, Target: targetNames[treeDir]})
+			index = len(version.Libraries) - 1
+		}
+		version.Libraries[index].BuildTypes = append(version.Libraries[index].BuildTypes, filepath.Base(buildTypeDir))
+	}
+
+	return version, nil
+}
+
+func printInstalledVersions(out io.Writer, versions []InstalledVersion) {
+	for i, version := range versions {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+
+		header := color.New(color.Bold).Sprint(version.Version)
+		if version.Pinned {
+			header += color.GreenString(" (pinned by this project)")
+		}
+		fmt.Fprintln(out, header)
+
+		installed := version.InstalledAt.Local().Format("2006-01-02 15:04")
+		if version.Commit != "" {
+			installed += " from commit " + shortCommit(version.Commit)
+		}
+		fmt.Fprintf(out, "  installed  %s\n", installed)
+
+		demos := "not installed"
+		if version.Demos {
+			demos = "installed"
+		}
+		fmt.Fprintf(out, "  demos      %s\n", demos)
+
+		if len(version.Libraries) == 0 {
+			fmt.Fprintln(out, "  libraries  none")
+			continue
+		}
+		fmt.Fprintln(out, "  libraries")
+		width := 0
+		for _, library := range version.Libraries {
+			width = max(width, len(library.Dir))
+		}
+		for _, library := range version.Libraries {
+			line := fmt.Sprintf("    %-*s  %s", width, library.Dir, strings.Join(library.BuildTypes, ", "))
+			if library.Target != "" {
+				line += color.HiBlackString("  (%s)", library.Target)
+			}
+			fmt.Fprintln(out, line)
+		}
+	}
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
@@ -0,0 +1,62 @@
+package manifest
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestListInstalledVersionsDescribesEachVersion verifies that every version
+// directory is listed with its library trees and build types, demos, and the
+// install details from its manifest, while staging directories and the tools
+// directory are skipped.
+func TestListInstalledVersionsDescribesEachVersion(t *testing.T) {
+	sdkRoot := t.TempDir()
+	installedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	current := filepath.Join(sdkRoot, "1.2.0")
+	writeTestFile(t, filepath.Join(current, "lib", "qt5", "yocto", "linux_arm_mconn", "debug", libraryFileName), "lib")
+	writeTestFile(t, filepath.Join(current, "lib", "qt5", "yocto", "linux_arm_mconn", "release", libraryFileName), "lib")
+	writeTestFile(t, filepath.Join(current, "lib", "qt6", "desktop", "linux_x86_64_desktop", "debug", libraryFileName), "lib")
+	writeTestFile(t, filepath.Join(current, "lib", "cmake", "mrs-sdk-qt", "config.cmake"), "config")
+	writeTestFile(t, filepath.Join(current, "demos", "README.md"), "demos")
+	manifest := &Manifest{SDKVersion: "1.2.0", Commit: "0123456789abcdef", InstalledAt: installedAt}
+	manifest.AddTargets(Target{Name: "mconn-yocto-qt5", BuildType: "debug", LibDir: "lib/qt5/yocto/linux_arm_mconn/debug"})
+	if err := manifest.Write(current); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	writeTestFile(t, filepath.Join(sdkRoot, "1.1.0", "include", "mrs-sdk-qt", "sdk.hpp"), "header")
+	writeTestFile(t, filepath.Join(sdkRoot, ".1.2.0.staging-123", "include", "mrs-sdk-qt", "sdk.hpp"), "header")
+	writeTestFile(t, filepath.Join(sdkRoot, toolsDirName, "mrs-sdk-manager"), "binary")
+
+	versions, err := listInstalledVersions(sdkRoot, "1.2.0")
+	if err != nil {
+		t.Fatalf("listInstalledVersions returned error: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != "1.1.0" || versions[1].Version != "1.2.0" {
+		t.Fatalf("expected versions 1.1.0 and 1.2.0, got %+v", versions)
+	}
+
+	old := versions[0]
+	if old.Pinned || old.Demos || old.Commit != "" || len(old.Libraries) != 0 || old.InstalledAt.IsZero() {
+		t.Fatalf("unexpected description of a version without manifest: %+v", old)
+	}
+
+	latest := versions[1]
+	if !latest.Pinned || !latest.Demos || latest.Commit != "0123456789abcdef" || !latest.InstalledAt.Equal(installedAt) {
+		t.Fatalf("unexpected description of the installed version: %+v", latest)
+	}
+	if len(latest.Libraries) != 2 {
+		t.Fatalf("expected two library trees, got %+v", latest.Libraries)
+	}
+	mconn := latest.Libraries[0]
+	if mconn.Dir != "lib/qt5/yocto/linux_arm_mconn" || mconn.Target != "mconn-yocto-qt5" || !slices.Equal(mconn.BuildTypes, []string{"debug", "release"}) {
+		t.Fatalf("unexpected library tree: %+v", mconn)
+	}
+	desktop := latest.Libraries[1]
+	if desktop.Dir != "lib/qt6/desktop/linux_x86_64_desktop" || desktop.Target != "" || !slices.Equal(desktop.BuildTypes, []string{"debug"}) {
+		t.Fatalf("unexpected library tree: %+v", desktop)
+	}
+}
@@ -0,0 +1,162 @@
+package manifest
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// FileName is the manifest written to the root of every installed SDK
+// version.
+const FileName = "manifest.json"
+
+// Manifest records what an installed SDK version contains and where it came
+// from, so that changes made to the installation afterwards can be detected.
+type Manifest struct {
+	SDKVersion     string            `json:"sdk_version"`
+	Commit         string            `json:"commit"` // Empty when installed outside a Git checkout
+	ManagerVersion string            `json:"manager_version"`
+	InstalledAt    time.Time         `json:"installed_at"`
+	Targets        []Target          `json:"targets"`
+	Files          map[string]string `json:"files"` // Slash-separated path in the version → SHA-256
+}
+
+// Target is a library installed in the SDK version.
+type Target struct {
+	Name      string `json:"name"`       // e.g. "mconn-yocto-qt5"
+	BuildType string `json:"build_type"` // "debug", "release" or "relwithdebinfo"
+	LibDir    string `json:"lib_dir"`    // Slash-separated directory of the library in the version
+}
+
+// Read loads the manifest of the SDK version installed in versionRoot.
+func Read(versionRoot string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(versionRoot, FileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(versionRoot, FileName), err)
+	}
+	return &manifest, nil
+}
+
+// Write saves the manifest to versionRoot.
+func (m *Manifest) Write(versionRoot string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(versionRoot, FileName), append(data, '\n'), 0644)
+}
+
+// AddTargets records the given targets, replacing earlier entries for the
+// same library directory. Targets are kept sorted.
+func (m *Manifest) AddTargets(targets ...Target) {
+	for _, target := range targets {
+		m.Targets = slices.DeleteFunc(m.Targets, func(existing Target) bool {
+			return existing.LibDir == target.LibDir
+		})
+		m.Targets = append(m.Targets, target)
+	}
+	slices.SortFunc(m.Targets, func(a, b Target) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.BuildType, b.BuildType))
+	})
+}
+
+// HashFiles returns the SHA-256 of every file in versionRoot except the
+// manifest itself, keyed by its slash-separated path.
+func HashFiles(versionRoot string) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.WalkDir(versionRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(versionRoot, path)
+		if err != nil {
+			return err
+		}
+		if relPath == FileName {
+			return nil
+		}
+
+		hash, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(relPath)] = hash
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash the files in %s: %w", versionRoot, err)
+	}
+	return files, nil
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Differences lists the files of an installed SDK version that do not match
+// its manifest. All paths are slash-separated and sorted.
+type Differences struct {
+	Missing    []string
+	Modified   []string
+	Unexpected []string
+}
+
+// Empty reports whether the installation matches its manifest.
+func (d Differences) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Modified) == 0 && len(d.Unexpected) == 0
+}
+
+// Compare checks the files in versionRoot against the manifest.
+func (m *Manifest) Compare(versionRoot string) (Differences, error) {
+	var differences Differences
+	files, err := HashFiles(versionRoot)
+	if err != nil {
+		return differences, err
+	}
+
+	for path, hash := range m.Files {
+		actual, ok := files[path]
+		switch {
+		case !ok:
+			differences.Missing = append(differences.Missing, path)
+		case actual != hash:
+			differences.Modified = append(differences.Modified, path)
+		}
+	}
+	for path := range files {
+		if _, ok := m.Files[path]; !ok {
+			differences.Unexpected = append(differences.Unexpected, path)
+		}
+	}
+
+	slices.Sort(differences.Missing)
+	slices.Sort(differences.Modified)
+	slices.Sort(differences.Unexpected)
+	return differences, nil
+}
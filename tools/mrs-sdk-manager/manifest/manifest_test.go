@@ -0,0 +1,85 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestCompareReportsChangedFiles verifies that files removed, edited or added
+// after the manifest was written are reported, and that the manifest itself
+// is not part of the comparison.
+func TestCompareReportsChangedFiles(t *testing.T) {
+	versionRoot := t.TempDir()
+	writeTestFile(t, filepath.Join(versionRoot, "include", "mrs-sdk-qt", "sdk.hpp"), "header")
+	writeTestFile(t, filepath.Join(versionRoot, "lib", "cmake", "mrs-sdk-qt", "config.cmake"), "config")
+	writeTestFile(t, filepath.Join(versionRoot, "lib", "qmake", "mrs-sdk-qt", "config.pri"), "config")
+
+	files, err := HashFiles(versionRoot)
+	if err != nil {
+		t.Fatalf("HashFiles returned error: %v", err)
+	}
+	manifest := &Manifest{SDKVersion: "1.2.0", Files: files}
+	if err := manifest.Write(versionRoot); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	differences, err := manifest.Compare(versionRoot)
+	if err != nil {
+		t.Fatalf("Compare returned error: %v", err)
+	}
+	if !differences.Empty() {
+		t.Fatalf("expected a fresh install to match, got %+v", differences)
+	}
+
+	writeTestFile(t, filepath.Join(versionRoot, "lib", "cmake", "mrs-sdk-qt", "config.cmake"), "edited by hand")
+	writeTestFile(t, filepath.Join(versionRoot, "lib", "cmake", "mrs-sdk-qt", "extra.cmake"), "extra")
+	if err := os.Remove(filepath.Join(versionRoot, "lib", "qmake", "mrs-sdk-qt", "config.pri")); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+
+	read, err := Read(versionRoot)
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	differences, err = read.Compare(versionRoot)
+	if err != nil {
+		t.Fatalf("Compare returned error: %v", err)
+	}
+	if !slices.Equal(differences.Missing, []string{"lib/qmake/mrs-sdk-qt/config.pri"}) ||
+		!slices.Equal(differences.Modified, []string{"lib/cmake/mrs-sdk-qt/config.cmake"}) ||
+		!slices.Equal(differences.Unexpected, []string{"lib/cmake/mrs-sdk-qt/extra.cmake"}) {
+		t.Fatalf("unexpected differences: %+v", differences)
+	}
+}
+
+// TestAddTargetsReplacesReinstalledLibraries verifies that installing a
+// target again does not record it twice.
+func TestAddTargetsReplacesReinstalledLibraries(t *testing.T) {
+	manifest := &Manifest{}
+	manifest.AddTargets(
+		Target{Name: "mconn-yocto-qt5", BuildType: "release", LibDir: "lib/qt5/yocto/linux_arm_mconn/release"},
+		Target{Name: "desktop-desktop-qt6", BuildType: "debug", LibDir: "lib/qt6/desktop/linux_x86_64_desktop/debug"},
+	)
+	manifest.AddTargets(Target{Name: "mconn-yocto-qt5", BuildType: "release", LibDir: "lib/qt5/yocto/linux_arm_mconn/release"})
+
+	var names []string
+	for _, target := range manifest.Targets {
+		names = append(names, target.Name+"-"+target.BuildType)
+	}
+	if !slices.Equal(names, []string{"desktop-desktop-qt6-debug", "mconn-yocto-qt5-release"}) {
+		t.Fatalf("unexpected targets: %v", names)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
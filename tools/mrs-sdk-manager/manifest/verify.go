@@ -0,0 +1,140 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mrs-sdk-manager/utils"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Verify checks an installed SDK version against its manifest and reports
+// missing, modified and unexpected files. Without a version, every installed
+// version is checked.
+func Verify(sdkVersion string) error {
+	sdkInstallRoot, err := utils.ResolveSDKInstallRoot()
+	if err != nil {
+		return err
+	}
+
+	versions := []string{sdkVersion}
+	if sdkVersion == "." || sdkVersion == ".." || strings.ContainsAny(sdkVersion, `/\`) {
+		return fmt.Errorf("invalid version %q", sdkVersion)
+	}
+	if sdkVersion == "" {
+		versions, err = InstalledVersions(sdkInstallRoot)
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			return fmt.Errorf("no SDK versions are installed in %s", sdkInstallRoot)
+		}
+	}
+
+	utils.PrintTaskStart(fmt.Sprintf("Verifying SDK installations in %s...", sdkInstallRoot))
+
+	failed := 0
+	for _, version := range versions {
+		ok, err := verifyVersion(color.Output, filepath.Join(sdkInstallRoot, version), version)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d SDK versions do not match their manifest", failed, len(versions))
+	}
+	utils.PrintSuccess("All SDK versions match their manifest")
+	return nil
+}
+
+// toolsDirName is the directory in MRS_SDK_QT_ROOT that holds the tools
+// installed independently of any SDK version, such as mrs-sdk-manager itself.
+const toolsDirName = "tools"
+
+// InstalledVersions returns the SDK versions installed in sdkInstallRoot. The
+// hidden directories used while an install is in progress, the tools
+// directory, and directories with neither a manifest nor the lib/ or include/
+// tree of an SDK version are skipped.
+func InstalledVersions(sdkInstallRoot string) ([]string, error) {
+	entries, err := os.ReadDir(sdkInstallRoot)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", sdkInstallRoot, err)
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || entry.Name() == toolsDirName {
+			continue
+		}
+		if isVersionDir(filepath.Join(sdkInstallRoot, entry.Name())) {
+			versions = append(versions, entry.Name())
+		}
+	}
+	return versions, nil
+}
+
+// isVersionDir reports whether dir looks like an installed SDK version.
+func isVersionDir(dir string) bool {
+	for _, name := range []string{FileName, "lib", "include"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyVersion prints the result for one SDK version and reports whether it
+// matches its manifest.
+func verifyVersion(out io.Writer, versionRoot, version string) (bool, error) {
+	if _, err := os.Stat(versionRoot); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, fmt.Errorf("SDK version %s is not installed (expected at %s)", version, versionRoot)
+		}
+		return false, err
+	}
+
+	manifest, err := Read(versionRoot)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintf(out, "%s %s: no %s; reinstall this version to create one\n", color.RedString("✗"), version, FileName)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	differences, err := manifest.Compare(versionRoot)
+	if err != nil {
+		return false, err
+	}
+	if differences.Empty() {
+		fmt.Fprintf(out, "%s %s: %d files match the manifest\n", color.GreenString("✓"), version, len(manifest.Files))
+		return true, nil
+	}
+
+	fmt.Fprintf(out, "%s %s:\n", color.RedString("✗"), version)
+	for _, group := range []struct {
+		label string
+		paths []string
+	}{
+		{"missing", differences.Missing},
+		{"modified", differences.Modified},
+		{"unexpected", differences.Unexpected},
+	} {
+		for _, path := range group.paths {
+			fmt.Fprintf(out, "  %-10s  %s\n", group.label, path)
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,43 @@
+package manifest
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestInstalledVersionsSkipsOtherDirectories verifies that the tools
+// directory, staging directories and directories that are not SDK versions
+// are not reported as installed versions, so that verifying every version
+// succeeds on a standard installation.
+func TestInstalledVersionsSkipsOtherDirectories(t *testing.T) {
+	sdkRoot := t.TempDir()
+	t.Setenv("MRS_SDK_QT_ROOT", sdkRoot)
+
+	writeTestFile(t, filepath.Join(sdkRoot, toolsDirName, "mrs-sdk-manager"), "binary")
+	writeTestFile(t, filepath.Join(sdkRoot, "notes", "todo.txt"), "notes")
+	writeTestFile(t, filepath.Join(sdkRoot, ".1.2.0.staging-123", "include", "mrs-sdk-qt", "sdk.hpp"), "header")
+
+	current := filepath.Join(sdkRoot, "1.2.0")
+	writeTestFile(t, filepath.Join(current, "include", "mrs-sdk-qt", "sdk.hpp"), "header")
+	files, err := HashFiles(current)
+	if err != nil {
+		t.Fatalf("HashFiles returned error: %v", err)
+	}
+	if err := (&Manifest{SDKVersion: "1.2.0", Files: files}).Write(current); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	if err := Verify(""); err != nil {
+		t.Fatalf("expected every installed version to verify, got error: %v", err)
+	}
+
+	writeTestFile(t, filepath.Join(sdkRoot, "1.1.0", "include", "mrs-sdk-qt", "sdk.hpp"), "header")
+	versions, err := InstalledVersions(sdkRoot)
+	if err != nil {
+		t.Fatalf("InstalledVersions returned error: %v", err)
+	}
+	if !slices.Equal(versions, []string{"1.1.0", "1.2.0"}) {
+		t.Fatalf("expected only the SDK versions, got %v", versions)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"mrs-sdk-manager/utils"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 
 	"github.com/fatih/color"
@@ -46,16 +47,54 @@ func Use(version string) error {
 		return fmt.Errorf("failed to get working directory: %w", err)
 	}
 
-	// Detect build systems
-	hasCMake := fileExists(filepath.Join(cwd, "CMakeLists.txt"))
-	hasQMake := hasQMakeProject(cwd)
+	if err := WriteProjectConfig(cwd, version); err != nil {
+		return err
+	}
+	hasCMake, hasQMake := DetectBuildSystems(cwd)
+
+	color.White("  Created mrs-sdk-qt/ configuration directory")
+
+	if hasCMake {
+		fmt.Println()
+		color.White("  CMake usage:")
+		color.White("    1. Set in Qt Creator kit: CMAKE_TOOLCHAIN_FILE = %%{sourceDir}/mrs-sdk-qt/toolchain.cmake")
+		color.White("    2. Set in Qt Creator kit: MRS_SDK_QT_TOOLCHAIN_ID = desktop-qt6 (or yocto-qt5, yocto-qt6, etc.)")
+		color.White("    3. In CMakeLists.txt, add:")
+		color.Cyan(`       include("mrs-sdk-qt/project.cmake")`)
+	}
+
+	if hasQMake {
+		fmt.Println()
+		color.White("  QMake usage:")
+		color.White("    1. Set in Qt Creator kit: MRS_SDK_QT_TOOLCHAIN_ID = desktop-qt6 (or yocto-qt5, yocto-qt6, etc.)")
+		color.White("    2. In your .pro file, add:")
+		color.Cyan(`       include("mrs-sdk-qt/toolchain.pri")`)
+		color.Cyan(`       include("mrs-sdk-qt/project.pri")`)
+	}
+
+	fmt.Println()
+
+	utils.PrintSuccess(fmt.Sprintf("Project configured to use SDK version %s", version))
+	return nil
+}
 
+// DetectBuildSystems reports whether projectDir contains a CMake project, a
+// QMake project, or both.
+func DetectBuildSystems(projectDir string) (hasCMake, hasQMake bool) {
+	return fileExists(filepath.Join(projectDir, "CMakeLists.txt")), hasQMakeProject(projectDir)
+}
+
+// WriteProjectConfig generates the mrs-sdk-qt/ configuration directory inside
+// projectDir for every build system the project uses. It does not check that
+// the version is installed.
+func WriteProjectConfig(projectDir, version string) error {
+	hasCMake, hasQMake := DetectBuildSystems(projectDir)
 	if !hasCMake && !hasQMake {
-		return fmt.Errorf("no CMakeLists.txt or .pro file found in %s", cwd)
+		return fmt.Errorf("no CMakeLists.txt or .pro file found in %s", projectDir)
 	}
 
 	// Create the mrs-sdk-qt/ directory
-	outputDir := filepath.Join(cwd, "mrs-sdk-qt")
+	outputDir := filepath.Join(projectDir, "mrs-sdk-qt")
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create mrs-sdk-qt directory: %w", err)
 	}
@@ -80,30 +119,27 @@ func Use(version string) error {
 		}
 	}
 
-	color.White("  Created mrs-sdk-qt/ configuration directory")
+	return nil
+}
 
-	if hasCMake {
-		fmt.Println()
-		color.White("  CMake usage:")
-		color.White("    1. Set in Qt Creator kit: CMAKE_TOOLCHAIN_FILE = %%{sourceDir}/mrs-sdk-qt/toolchain.cmake")
-		color.White("    2. Set in Qt Creator kit: MRS_SDK_QT_TOOLCHAIN_ID = desktop-qt6 (or yocto-qt5, etc.)")
-		color.White("    3. In CMakeLists.txt, add:")
-		color.Cyan(`       include("mrs-sdk-qt/project.cmake")`)
+// PinnedVersion returns the SDK version that projectDir pins in
+// mrs-sdk-qt/version.conf, or an empty string if the project has not been
+// configured with `mrs-sdk-manager use`.
+func PinnedVersion(projectDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, "mrs-sdk-qt", "version.conf"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
 	}
 
-	if hasQMake {
-		fmt.Println()
-		color.White("  QMake usage:")
-		color.White("    1. Set in Qt Creator kit: MRS_SDK_QT_TOOLCHAIN_ID = desktop-qt6 (or yocto-qt5, etc.)")
-		color.White("    2. In your .pro file, add:")
-		color.Cyan(`       include("mrs-sdk-qt/toolchain.pri")`)
-		color.Cyan(`       include("mrs-sdk-qt/project.pri")`)
+	for _, line := range strings.Split(string(data), "\n") {
+		if version, ok := strings.CutPrefix(strings.TrimSpace(line), "MRS_SDK_QT_VERSION="); ok {
+			return version, nil
+		}
 	}
-
-	fmt.Println()
-
-	utils.PrintSuccess(fmt.Sprintf("Project configured to use SDK version %s", version))
-	return nil
+	return "", nil
 }
 
 func fileExists(path string) bool {
@@ -0,0 +1,33 @@
+package utils
+
+import "runtime/debug"
+
+// ManagerVersion returns the version of this mrs-sdk-manager binary: the
+// module version when it was installed with `go install`, otherwise the
+// commit it was built from.
+func ManagerVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+
+	revision, modified := "", false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision == "" {
+		return "(devel)"
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return revision
+}
@@ -17,5 +17,5 @@ func PrintSuccess(message string) {
 func PrintError(title, message string) {
 	color.New(color.FgRed, color.Bold).Printf("\n===== %s\n", title)
 	color.Red(message)
-	fmt.Println()
+	fmt.Fprintln(color.Output)
 }